@@ -0,0 +1,202 @@
+// gocldb batch lookups with a worker pool
+
+package gocldb
+
+import (
+	"context"
+	"iter"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A callsign and contact/QSO time to look up
+// Note well: callsign must be uppercased
+type Query struct {
+	Call    string
+	QsoTime time.Time
+}
+
+// Result of a Query
+type Result struct {
+	// The looked up Query
+	Query Query
+	// CheckCallsign() result
+	CLDCheckResult
+	// CheckCallsign() error, e.g., ErrNoMatch, or the context error
+	// if cancelled before looked up (Status is StatusUnknown)
+	Err error
+}
+
+// Batch lookup settings
+// The zero value uses the default Database
+// and runtime.GOMAXPROCS(0) workers
+type Batch struct {
+	// Database to look up
+	// If nil, the default Database at the start of each batch is used,
+	// so a batch sees the same tables during concurrent reloads
+	Database *Database
+	// Number of worker goroutines
+	// If zero or negative, runtime.GOMAXPROCS(0) is used
+	Workers int
+}
+
+// Returns the Database for a batch
+func (b *Batch) database() *Database {
+	if b.Database != nil {
+		return b.Database
+	}
+	return defaultDatabase.Load()
+}
+
+// Returns the number of workers for a batch
+func (b *Batch) workers() int {
+	if b.Workers > 0 {
+		return b.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// Look up the queries in parallel
+// Returns the results in the same order as queries
+// If ctx is cancelled, the remaining queries are not looked up
+// and their results have the context error in Err
+func (b *Batch) CheckCallsigns(ctx context.Context, queries []Query) []Result {
+	db := b.database()
+	results := make([]Result, len(queries))
+	done := make([]bool, len(queries))
+	workers := min(b.workers(), len(queries))
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1)) - 1
+				if i >= len(queries) {
+					return
+				}
+				q := queries[i]
+				result, err := db.CheckCallsignContext(ctx, q.Call, q.QsoTime)
+				results[i] = Result{Query: q, CLDCheckResult: result, Err: err}
+				done[i] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, q := range queries {
+		if !done[i] {
+			results[i] = Result{Query: q, Err: ctx.Err()}
+		}
+	}
+	return results
+}
+
+// A query and the channel to send its result
+type batchJob struct {
+	query  Query
+	result chan Result
+}
+
+// Look up the queries in parallel and send the results to the returned channel
+// in the same order as queries
+// The channel is closed after all results are sent,
+// or promptly after ctx is cancelled
+// Note well: the caller must cancel ctx if it stops receiving
+// before the channel is closed, or the goroutines are left blocked;
+// queries should not block without watching ctx either
+func (b *Batch) Stream(ctx context.Context, queries iter.Seq[Query]) <-chan Result {
+	db := b.database()
+	workers := b.workers()
+	jobs := make(chan batchJob)
+	// Result channels in the query order
+	pending := make(chan chan Result, workers)
+	out := make(chan Result)
+
+	// Feed the queries to the workers
+	go func() {
+		defer close(jobs)
+		defer close(pending)
+		for q := range queries {
+			// Stop taking queries after cancelled
+			if ctx.Err() != nil {
+				return
+			}
+			job := batchJob{query: q, result: make(chan Result, 1)}
+			select {
+			case pending <- job.result:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			for job := range jobs {
+				q := job.query
+				result, err := db.CheckCallsignContext(ctx, q.Call, q.QsoTime)
+				job.result <- Result{Query: q, CLDCheckResult: result, Err: err}
+			}
+		}()
+	}
+
+	// Send the results in the query order
+	go func() {
+		defer close(out)
+		for rc := range pending {
+			var r Result
+			select {
+			case r = <-rc:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case out <- r:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Look up the queries in parallel and return the results
+// in the same order as queries
+// Breaking the loop stops the lookups
+// See (*Batch).Stream()
+func (b *Batch) All(ctx context.Context, queries iter.Seq[Query]) iter.Seq[Result] {
+	return func(yield func(Result) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		for r := range b.Stream(ctx, queries) {
+			if !yield(r) {
+				return
+			}
+		}
+	}
+}
+
+// Look up the queries in parallel with db
+// See (*Batch).CheckCallsigns()
+func (db *Database) CheckCallsigns(ctx context.Context, queries []Query) []Result {
+	b := Batch{Database: db}
+	return b.CheckCallsigns(ctx, queries)
+}
+
+// Look up the queries in parallel with the default Database
+// See (*Batch).CheckCallsigns()
+func CheckCallsigns(ctx context.Context, queries []Query) []Result {
+	var b Batch
+	return b.CheckCallsigns(ctx, queries)
+}
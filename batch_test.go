@@ -0,0 +1,107 @@
+package gocldb
+
+import (
+	"context"
+	"iter"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Endless queries of distinct callsigns
+func endlessQueries(qsotime time.Time) iter.Seq[Query] {
+	return func(yield func(Query) bool) {
+		for i := 0; ; i++ {
+			if !yield(Query{Call: "JA" + strconv.Itoa(i%10) + "ABC", QsoTime: qsotime}) {
+				return
+			}
+		}
+	}
+}
+
+// Wait until the number of goroutines is back to n
+func waitGoroutines(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			t.Errorf("%d goroutines left running, want %d", runtime.NumGoroutine(), n)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBatchStreamOrder(t *testing.T) {
+	db := loadTestDatabase(t)
+	qsotime := mustTime(t, "2023-01-15T00:00:00Z")
+	var queries []Query
+	for i := range 1000 {
+		queries = append(queries, Query{Call: "JA" + strconv.Itoa(i) + "X", QsoTime: qsotime})
+	}
+	for _, workers := range []int{1, 4, 16} {
+		b := &Batch{Database: db, Workers: workers}
+		ch := b.Stream(context.Background(), func(yield func(Query) bool) {
+			for _, q := range queries {
+				if !yield(q) {
+					return
+				}
+			}
+		})
+		var n int
+		for r := range ch {
+			if n < len(queries) && r.Query != queries[n] {
+				t.Fatalf("workers %d: result %d = %s, want %s", workers, n, r.Query.Call, queries[n].Call)
+			}
+			n++
+		}
+		if n != len(queries) {
+			t.Errorf("workers %d: %d results, want %d", workers, n, len(queries))
+		}
+	}
+}
+
+func TestBatchStreamCancel(t *testing.T) {
+	db := loadTestDatabase(t)
+	qsotime := mustTime(t, "2023-01-15T00:00:00Z")
+	before := runtime.NumGoroutine()
+
+	// The channel is closed after cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Batch{Database: db, Workers: 4}
+	ch := b.Stream(ctx, endlessQueries(qsotime))
+	for range 10 {
+		<-ch
+	}
+	cancel()
+	timeout := time.After(5 * time.Second)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-ch:
+			closed = !ok
+		case <-timeout:
+			t.Fatal("Stream() not closed after cancelled")
+		}
+	}
+	waitGoroutines(t, before)
+
+	// The goroutines exit after cancelled without receiving
+	ctx, cancel = context.WithCancel(context.Background())
+	ch = b.Stream(ctx, endlessQueries(qsotime))
+	<-ch
+	cancel()
+	waitGoroutines(t, before)
+
+	// Breaking out of All() stops the workers
+	var n int
+	for r := range b.All(context.Background(), endlessQueries(qsotime)) {
+		if r.Err != nil || r.Adif != 339 {
+			t.Fatalf("All() result %d = %d, %v", n, r.Adif, r.Err)
+		}
+		if n++; n == 100 {
+			break
+		}
+	}
+	waitGoroutines(t, before)
+}
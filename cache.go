@@ -0,0 +1,178 @@
+// gocldb memoizing LRU cache of callsign check results
+
+package gocldb
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Counters of a Cache
+type CacheStats struct {
+	// Lookups answered from the cache
+	Hits uint64
+	// Lookups checked with the Database
+	Misses uint64
+	// Entries removed to keep the size
+	Evictions uint64
+	// Times the cache was cleared for a reloaded Database
+	Invalidations uint64
+	// Current number of entries
+	Len int
+}
+
+// A cached result of a callsign
+// valid for the QSO times between start and end
+type cacheEntry struct {
+	call   string
+	start  time.Time
+	end    time.Time
+	result CLDCheckResult
+	err    error
+}
+
+// Bounded LRU cache in front of CheckCallsign()
+// A result is cached with the time range where the matched
+// (and unmatched) records of the callsign stay the same,
+// so a lookup of the same callsign at another QSO time
+// in the range is a hit
+// The cache is cleared when the Database is replaced, e.g., reloaded
+// Safe for concurrent use
+type Cache struct {
+	mu     sync.Mutex
+	size   int
+	source func() *Database
+	// Database of the cached results
+	db *Database
+	// Entries in the recently used order, front is the newest
+	lru *list.List
+	// Entries by callsign
+	calls map[string][]*list.Element
+	stats CacheStats
+}
+
+// Returns a Cache of up to size entries
+// looking up the Database returned by source
+// If source is nil, the default Database is used,
+// e.g., use (*Watcher).Database for a Watcher
+func NewCache(size int, source func() *Database) *Cache {
+	if source == nil {
+		source = DefaultDatabase
+	}
+	return &Cache{
+		size:   max(size, 1),
+		source: source,
+		lru:    list.New(),
+		calls:  make(map[string][]*list.Element),
+	}
+}
+
+// Parse a callsign and time through the cache
+// The results are the same as (*Database).CheckCallsign()
+// Note well: callsign must be uppercased
+func (c *Cache) CheckCallsign(call string, qsotime time.Time) (CLDCheckResult, error) {
+	db := c.source()
+
+	c.mu.Lock()
+	if c.db != db {
+		c.clear()
+		if c.db != nil {
+			c.stats.Invalidations++
+		}
+		c.db = db
+	}
+	if ce := c.find(call, qsotime); ce != nil {
+		c.stats.Hits++
+		c.mu.Unlock()
+		return ce.result, ce.err
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	lc := newLookupContext()
+	result, err := db.checkCallsign(context.Background(), call, qsotime, lc)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Do not cache the result of an old Database,
+	// nor add a duplicate entry when another goroutine
+	// has cached the callsign for qsotime in the meantime
+	if c.db == db && c.find(call, qsotime) == nil {
+		c.add(&cacheEntry{
+			call:   call,
+			start:  lc.start,
+			end:    lc.end,
+			result: result,
+			err:    err,
+		})
+	}
+	return result, err
+}
+
+// Returns the entry of call covering qsotime
+// and marks it as the most recently used, or nil if not found
+// Call with c.mu locked
+func (c *Cache) find(call string, qsotime time.Time) *cacheEntry {
+	for _, e := range c.calls[call] {
+		ce := e.Value.(*cacheEntry)
+		if timeInRange(qsotime, ce.start, ce.end) {
+			c.lru.MoveToFront(e)
+			return ce
+		}
+	}
+	return nil
+}
+
+// Add an entry and evict the least recently used ones
+// Call with c.mu locked
+func (c *Cache) add(ce *cacheEntry) {
+	c.calls[ce.call] = append(c.calls[ce.call], c.lru.PushFront(ce))
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Remove an entry
+// Call with c.mu locked
+func (c *Cache) remove(e *list.Element) {
+	ce := c.lru.Remove(e).(*cacheEntry)
+	elements := c.calls[ce.call]
+	for i, x := range elements {
+		if x == e {
+			elements = append(elements[:i], elements[i+1:]...)
+			break
+		}
+	}
+	if len(elements) == 0 {
+		delete(c.calls, ce.call)
+	} else {
+		c.calls[ce.call] = elements
+	}
+}
+
+// Remove all entries
+// Call with c.mu locked
+func (c *Cache) clear() {
+	c.lru.Init()
+	clear(c.calls)
+}
+
+// Remove all entries
+// The counters are kept
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clear()
+}
+
+// Returns the counters
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Len = c.lru.Len()
+	return stats
+}
@@ -0,0 +1,126 @@
+package gocldb
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// QSO times around the edges of the validity ranges of the fixture
+var cacheEdgeTimes = []string{
+	"1999-12-31T23:59:59Z",
+	"2000-01-01T00:00:00Z",
+	"2001-12-31T23:59:59Z",
+	"2002-01-01T00:00:00Z",
+	"2018-12-31T23:59:59Z",
+	"2019-01-01T00:00:00Z",
+	"2019-01-15T00:00:00Z",
+	"2019-01-31T23:59:59Z",
+	"2019-02-01T00:00:00Z",
+	"2020-06-01T00:00:00Z",
+	"2021-01-01T00:00:00Z",
+	"2021-01-31T23:59:59Z",
+	"2021-02-01T00:00:00Z",
+	"2021-12-31T23:59:59Z",
+	"2022-01-01T00:00:00Z",
+	"2022-12-31T23:59:59Z",
+	"2023-01-01T00:00:00Z",
+}
+
+// Calls with records of limited validity ranges in the fixture
+// and calls the Database does not normalize
+var cacheEdgeCalls = []string{"JA1XYZ", "JA1INV", "KL7ABC", "BS7H", "JA1ABC", "QQ1ABC",
+	"ja1xyz", " JA1XYZ"}
+
+func TestCacheEdges(t *testing.T) {
+	db := loadTestDatabase(t)
+	var times []time.Time
+	for _, s := range cacheEdgeTimes {
+		times = append(times, mustTime(t, s))
+	}
+	// Fill the cache in the ascending and descending orders of time
+	orders := [][]time.Time{times, slices.Clone(times)}
+	slices.Reverse(orders[1])
+
+	for _, order := range orders {
+		c := NewCache(1000, func() *Database { return db })
+		// The second pass is answered from the cache
+		for range 2 {
+			for _, call := range cacheEdgeCalls {
+				for _, qsotime := range order {
+					got, gerr := c.CheckCallsign(call, qsotime)
+					want, werr := db.CheckCallsign(call, qsotime)
+					if !reflect.DeepEqual(got, want) || !errors.Is(gerr, werr) {
+						t.Errorf("%s at %v: cached %+v, %v, want %+v, %v",
+							call, qsotime, got, gerr, want, werr)
+					}
+				}
+			}
+		}
+		stats := c.Stats()
+		if stats.Hits < stats.Misses {
+			t.Errorf("Stats() = %+v, want more hits than misses", stats)
+		}
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	db := loadTestDatabase(t)
+	overlaid := db.WithOverlay(nil)
+	err := overlaid.LoadOverlayFile(testOverlayXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	current := db
+	c := NewCache(10, func() *Database {
+		mu.Lock()
+		defer mu.Unlock()
+		return current
+	})
+	qsotime := mustTime(t, "2023-01-15T00:00:00Z")
+
+	r, err := c.CheckCallsign("JA1ZZZ", qsotime)
+	if err != nil || r.Adif != 339 {
+		t.Fatalf("JA1ZZZ: Adif = %d, %v, want 339", r.Adif, err)
+	}
+	mu.Lock()
+	current = overlaid
+	mu.Unlock()
+	r, err = c.CheckCallsign("JA1ZZZ", qsotime)
+	if err != nil || r.Adif != 192 {
+		t.Errorf("JA1ZZZ after the swap: Adif = %d, %v, want 192", r.Adif, err)
+	}
+	stats := c.Stats()
+	if stats.Invalidations != 1 || stats.Misses != 2 || stats.Len != 1 {
+		t.Errorf("Stats() = %+v, want 1 invalidation, 2 misses, and 1 entry", stats)
+	}
+}
+
+// Concurrent misses of the same callsign and time
+// must not add duplicate entries
+func TestCacheConcurrentMisses(t *testing.T) {
+	db := loadTestDatabase(t)
+	qsotime := mustTime(t, "2023-01-15T00:00:00Z")
+	for range 100 {
+		c := NewCache(100, func() *Database { return db })
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for range 16 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				c.CheckCallsign("W1AW/2", qsotime)
+			}()
+		}
+		close(start)
+		wg.Wait()
+		if stats := c.Stats(); stats.Len != 1 {
+			t.Fatalf("Stats() = %+v, want 1 entry", stats)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// gocldb hand-written callsign scanners
+// These replace the regular expressions in the lookup path
+// so that the lookups do not compile or allocate anything
+
+package gocldb
+
+import (
+	"strings"
+)
+
+// Check if c is a capital letter
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+// Check if c is a digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Check if a callsign consists of
+// digits, capital letters, and slashes only
+// from length 1 to CallsignMaxLength characters
+// Same as `^[0-9|A-Z|\/]{1,16}$` (including '|')
+func isCallsignChars(call string) bool {
+	l := len(call)
+	if l < 1 || l > CallsignMaxLength {
+		return false
+	}
+	for i := 0; i < l; i++ {
+		c := call[i]
+		if !isDigit(c) && !isUpper(c) && c != '/' && c != '|' {
+			return false
+		}
+	}
+	return true
+}
+
+// Check if s consists of n or more capital letters
+// Same as `^[A-Z]{n,}$`
+func isAlphas(s string, n int) bool {
+	if len(s) < n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isUpper(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Check if s consists of n or more digits
+// Same as `^[0-9]{n,}$`
+func isDigits(s string, n int) bool {
+	if len(s) < n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Check if s is a Maritime Mobile suffix
+// Same as `^MM[0-9]?$`
+func isMaritimeMobile(s string) bool {
+	return (len(s) == 2 || (len(s) == 3 && isDigit(s[2]))) &&
+		s[0] == 'M' && s[1] == 'M'
+}
+
+// Scan a callsign-like string into
+// letter prefix, call area digits, and suffix
+// Same as `^([0-9]?[A-Z]+)([0-9]+)([0-9A-Z]+)$`
+// Returns the end positions of the letter prefix
+// and of the call area digits, and true if matched
+func scanCallArea(call string) (int, int, bool) {
+	l := len(call)
+	for i := 0; i < l; i++ {
+		if !isDigit(call[i]) && !isUpper(call[i]) {
+			return 0, 0, false
+		}
+	}
+	i := 0
+	if i < l && isDigit(call[i]) {
+		i++
+	}
+	j := i
+	for j < l && isUpper(call[j]) {
+		j++
+	}
+	if j == i {
+		return 0, 0, false
+	}
+	k := j
+	for k < l && isDigit(call[k]) {
+		k++
+	}
+	// Leave at least one character for the suffix
+	if k == l {
+		k--
+	}
+	if k <= j {
+		return 0, 0, false
+	}
+	return j, k, true
+}
+
+// Split a callsign separated by "/" into parts appended to buf
+// Same as strings.Split(call, "/"),
+// without allocation if buf has enough capacity
+func splitCallparts(buf []string, call string) []string {
+	for {
+		i := strings.IndexByte(call, '/')
+		if i < 0 {
+			return append(buf, call)
+		}
+		buf = append(buf, call[:i])
+		call = call[i+1:]
+	}
+}
+
+// Length of the callsign rebuilt from the parts
+// joined with "/"
+func callpartsLength(callparts []string) int {
+	n := len(callparts) - 1
+	for _, s := range callparts {
+		n += len(s)
+	}
+	return n
+}
@@ -0,0 +1,94 @@
+package gocldb
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Calls all the strings of the characters of alphabet
+// from length 0 to maxlen with f
+func forAllStrings(alphabet string, maxlen int, f func(string)) {
+	var gen func(prefix string)
+	gen = func(prefix string) {
+		f(prefix)
+		if len(prefix) == maxlen {
+			return
+		}
+		for i := 0; i < len(alphabet); i++ {
+			gen(prefix + alphabet[i:i+1])
+		}
+	}
+	gen("")
+}
+
+// Characters of the test strings: letters, digits, separators,
+// and characters not allowed in callsigns
+const scanAlphabet = "AMZ059/|a-"
+
+func TestScannersRegexp(t *testing.T) {
+	// The regular expressions replaced by the scanners
+	callcheck := regexp.MustCompile(`^[0-9|A-Z|\/]{1,16}$`)
+	threealphas := regexp.MustCompile(`^[A-Z]{3,}$`)
+	twodigits := regexp.MustCompile(`^[0-9]{2,}$`)
+	mmcheck := regexp.MustCompile(`^MM[0-9]?$`)
+
+	forAllStrings(scanAlphabet, 5, func(s string) {
+		if got, want := isCallsignChars(s), callcheck.MatchString(s); got != want {
+			t.Errorf("isCallsignChars(%q) = %v, want %v", s, got, want)
+		}
+		if got, want := isAlphas(s, 3), threealphas.MatchString(s); got != want {
+			t.Errorf("isAlphas(%q, 3) = %v, want %v", s, got, want)
+		}
+		if got, want := isDigits(s, 2), twodigits.MatchString(s); got != want {
+			t.Errorf("isDigits(%q, 2) = %v, want %v", s, got, want)
+		}
+		if got, want := isMaritimeMobile(s), mmcheck.MatchString(s); got != want {
+			t.Errorf("isMaritimeMobile(%q) = %v, want %v", s, got, want)
+		}
+	})
+
+	// Length limit of callsigns
+	for n := 0; n <= CallsignMaxLength+2; n++ {
+		for _, c := range []string{"A", "0", "/", "|", "a"} {
+			s := strings.Repeat(c, n)
+			if got, want := isCallsignChars(s), callcheck.MatchString(s); got != want {
+				t.Errorf("isCallsignChars(%q) = %v, want %v", s, got, want)
+			}
+			s = "JA1" + s
+			if got, want := isCallsignChars(s), callcheck.MatchString(s); got != want {
+				t.Errorf("isCallsignChars(%q) = %v, want %v", s, got, want)
+			}
+		}
+	}
+}
+
+func TestScanCallAreaRegexp(t *testing.T) {
+	// The regular expressions replaced by scanCallArea()
+	prefixnumsuffix := regexp.MustCompile(`^([0-9]?[A-Z]+)([0-9]+)([0-9A-Z]+)$`)
+	prefixsuffix := regexp.MustCompile(`^([0-9]?[A-Z]+[0-9]+)([0-9A-Z]+)$`)
+
+	check := func(s string) {
+		j, k, matched := scanCallArea(s)
+		m := prefixnumsuffix.FindStringSubmatch(s)
+		if matched != (m != nil) {
+			t.Errorf("scanCallArea(%q) matched = %v, want %v", s, matched, m != nil)
+			return
+		}
+		if matched && (s[:j] != m[1] || s[j:k] != m[2] || s[k:] != m[3]) {
+			t.Errorf("scanCallArea(%q) = %q %q %q, want %q %q %q",
+				s, s[:j], s[j:k], s[k:], m[1], m[2], m[3])
+		}
+		prefix, suffix := splitCallsign(s)
+		var wprefix, wsuffix string
+		if m := prefixsuffix.FindStringSubmatch(s); m != nil {
+			wprefix, wsuffix = m[1], m[2]
+		}
+		if prefix != wprefix || suffix != wsuffix {
+			t.Errorf("splitCallsign(%q) = %q %q, want %q %q", s, prefix, suffix, wprefix, wsuffix)
+		}
+	}
+	forAllStrings(scanAlphabet, 5, check)
+	// Longer strings of letters and digits only
+	forAllStrings("AZ09", 8, check)
+}
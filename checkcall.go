@@ -3,11 +3,10 @@
 package gocldb
 
 import (
+	"context"
 	"errors"
-	"regexp"
 	"strings"
 	"time"
-	"unicode"
 )
 
 const (
@@ -49,6 +48,21 @@ type CLDCheckResult struct {
 	BlockedByWhitelist bool
 	// True if DXCC-invalid QSO
 	Invalid bool
+	// True if a record of the overlay matched
+	Overlay bool
+	// Outcome of the check
+	Status Status
+	// Record numbers of the matched records (0 if none)
+	ExceptionRecord     uint64
+	PrefixRecord        uint64
+	InvalidRecord       uint64
+	ZoneExceptionRecord uint64
+	// Callsign or prefix of the matched exception,
+	// prefix, or invalid operation record
+	MatchedKey string
+	// Validity time range of the matched record of MatchedKey
+	MatchedStart time.Time
+	MatchedEnd   time.Time
 	// Private members listed below
 	// CLDException info if applicable
 	hasRecordException bool
@@ -71,6 +85,15 @@ func initCLDCheckResult() CLDCheckResult {
 	v.Deleted = false
 	v.BlockedByWhitelist = false
 	v.Invalid = false
+	v.Overlay = false
+	v.Status = StatusUnknown
+	v.ExceptionRecord = 0
+	v.PrefixRecord = 0
+	v.InvalidRecord = 0
+	v.ZoneExceptionRecord = 0
+	v.MatchedKey = ""
+	v.MatchedStart = time.Time{}
+	v.MatchedEnd = time.Time{}
 	v.hasRecordException = false
 	v.hasRecordZoneException = false
 	v.hasRecordInvalid = false
@@ -80,6 +103,7 @@ func initCLDCheckResult() CLDCheckResult {
 
 // Errors
 var ErrMalformedCallsign = errors.New("Malformed callsign")
+var ErrNoMatch = errors.New("No matching record")
 var ErrNotReached = errors.New("Jumped into unreachable code")
 
 // Check if a given time is in the time range
@@ -91,103 +115,48 @@ func timeInRange(t time.Time, lower time.Time, upper time.Time) bool {
 // Check if a callsign and a given time is in CLDMapException
 // Returns CLDMapException and bool
 // If bool is true, the match exists; if false, did not matched
-func inExceptionMap(call string, t time.Time) (CLDException, bool) {
-	exceptions, refexists := CLDMapException[call]
-	if !refexists {
-		return CLDException{}, false
-	}
-	// Scan the result slice to find out whether the matching period exists
-	// Return the first matched result
-	for _, s := range exceptions {
-		if timeInRange(t, s.Start, s.End) {
-			return s, true
-		}
-	}
-	// If not found, return so
-	return CLDException{}, false
+func (db *Database) inExceptionMap(call string, t time.Time, lc *lookupContext) (CLDException, bool) {
+	return db.exceptionIndex.find(call, t, lc)
 }
 
 // Check if a callsign and a given time is in CLDZoneException
 // Returns CLDZoneException and bool
 // If bool is true, the match exists; if false, did not matched
-func inZoneExceptionMap(call string, t time.Time) (CLDZoneException, bool) {
-	exceptions, refexists := CLDMapZoneException[call]
-	if !refexists {
-		return CLDZoneException{}, false
-	}
-	// Scan the result slice to find out whether the matching period exists
-	// Return the first matched result
-	for _, s := range exceptions {
-		if timeInRange(t, s.Start, s.End) {
-			return s, true
-		}
-	}
-	// If not found, return so
-	return CLDZoneException{}, false
+func (db *Database) inZoneExceptionMap(call string, t time.Time, lc *lookupContext) (CLDZoneException, bool) {
+	return db.zoneExceptionIndex.find(call, t, lc)
 }
 
 // Check if a callsign and a given time is in CLDMapInvalid
 // Returns CLDInvalid and bool
 // If bool is true, the match exists; if false, did not matched
-func inInvalidMap(call string, t time.Time) (CLDInvalid, bool) {
-	exceptions, refexists := CLDMapInvalid[call]
-	if !refexists {
-		return CLDInvalid{}, false
-	}
-	// Scan the result slice to find out whether the matching period exists
-	// Return the first matched result
-	for _, s := range exceptions {
-		if timeInRange(t, s.Start, s.End) {
-			return s, true
-		}
-	}
-	// If not found, return so
-	return CLDInvalid{}, false
+func (db *Database) inInvalidMap(call string, t time.Time, lc *lookupContext) (CLDInvalid, bool) {
+	return db.invalidIndex.find(call, t, lc)
 }
 
 // Check the longest prefix match
-// of a given callsign in CLDMapPrefixNoSlash
+// of a given callsign in CLDMapPrefix
 // Returns the matched prefix, corresponding CLDPrefix, and bool
 // If bool is true, the match exists; if false, did not matched
 // How to search:
-// You need to scan and list all the possible prefixes
-// and look them up from the longer to the shorter ones
-// to find the longest matched prefix with the time range matching
-func inPrefixMap(call string, t time.Time) (string, CLDPrefix, bool) {
-	matched := make(map[int]string, 4)
-	ml := 0
-	// Search all map entries for matched prefixes
-	for p := range CLDMapPrefix {
-		if strings.HasPrefix(call, p) {
-			pl := len(p)
-			matched[pl] = p
-			if ml < pl {
-				ml = pl
-			}
+// The prefix trie built by BuildIndex() is walked
+// along the characters of the callsign
+// to find the longest matched prefix with the time range matching;
+// if the time range does not match, shorter prefixes are tried
+func (db *Database) inPrefixMap(call string, t time.Time, lc *lookupContext) (string, CLDPrefix, bool) {
+	if db.prefixTrie == nil {
+		lc.log("inPrefixMap: no index built")
+		return "", CLDPrefix{}, false
+	}
+	p, s, found := db.prefixTrie.lookup(call, t, lc)
+	if found {
+		if lc.debug() {
+			lc.log("inPrefixMap: matched", "prefix", p, "record", s.Record, "adif", s.Adif)
 		}
+		return p, s, true
 	}
-	DebugLogger.Printf("inPrefixMap matched: %#v\n", matched)
-	// Sort matched prefixes into longest to shortset order
-	prefixes := make([]string, 0, 8)
-	for i := ml; i > 0; i-- {
-		p, exists := matched[i]
-		if exists {
-			prefixes = append(prefixes, p)
-		}
-	}
-	DebugLogger.Printf("inPrefixMap prefixes: %#v\n", prefixes)
-	// Search if a matched time entry exists in a prefix
-	// and if exists return the result
-	for _, p := range prefixes {
-		entry := CLDMapPrefix[p]
-		for _, s := range entry {
-			if timeInRange(t, s.Start, s.End) {
-				DebugLogger.Printf("inPrefixMap s: %#v\n", s)
-				return p, s, true
-			}
-		}
+	if lc.debug() {
+		lc.log("inPrefixMap: unable to match prefix", "call", call)
 	}
-	DebugLogger.Printf("inPrefixMap unable to match prefix\n")
 	return "", CLDPrefix{}, false
 }
 
@@ -202,59 +171,76 @@ var distractionSuffixes = map[string]bool{
 }
 
 // Remove unnecessary distraction suffix
-func removeDistractionSuffix(callparts []string) ([]string, bool) {
+func (db *Database) removeDistractionSuffix(callparts []string, lc *lookupContext) ([]string, bool) {
 	l := len(callparts)
 	if l < 2 {
 		return callparts, false
 	}
 	p := l - 1
 	s := callparts[p]
-	DebugLogger.Printf("removeDistractionSuffix: p: %d, s: %s, ", p, s)
+	if lc.debug() {
+		lc.log("removeDistractionSuffix", "part", p, "suffix", s)
+	}
 
 	// Remove single suffix in the list
 	if distractionSuffixes[s] {
 		callparts2 := callparts[:p]
-		DebugLogger.Printf("callparts: %#v\n", callparts2)
+		lc.addStep(ExplainStep{Step: StepDistractionSuffix, Call: s, Matched: true, Detail: "listed suffix"})
+		if lc.debug() {
+			lc.log("removeDistractionSuffix: removed", "callparts", strings.Join(callparts2, "/"))
+		}
 		return callparts2, true
 	}
 	// Remove three or more alphabet-only letter suffix
-	threealphas := regexp.MustCompile(`^[A-Z]{3,}$`)
-	// If not, return with malformed callsign error
-	if threealphas.MatchString(s) {
+	if isAlphas(s, 3) {
 		callparts2 := callparts[:p]
-		DebugLogger.Printf("callparts: %#v\n", callparts2)
+		lc.addStep(ExplainStep{Step: StepDistractionSuffix, Call: s, Matched: true, Detail: "three or more letters"})
+		if lc.debug() {
+			lc.log("removeDistractionSuffix: removed", "callparts", strings.Join(callparts2, "/"))
+		}
 		return callparts2, true
 	}
 	// Remove two or more digit-only letter suffix
-	twodigits := regexp.MustCompile(`^[0-9]{2,}$`)
-	if twodigits.MatchString(s) {
+	if isDigits(s, 2) {
 		callparts2 := callparts[:p]
-		DebugLogger.Printf("callparts: %#v\n", callparts2)
+		lc.addStep(ExplainStep{Step: StepDistractionSuffix, Call: s, Matched: true, Detail: "two or more digits"})
+		if lc.debug() {
+			lc.log("removeDistractionSuffix: removed", "callparts", strings.Join(callparts2, "/"))
+		}
 		return callparts2, true
 	}
 	// Remove "/M/P", "/P/M", "/A/M"
 	if l >= 3 {
 		p2 := l - 2
 		s2 := callparts[p2]
-		DebugLogger.Printf("removeDistractionSuffix: p2: %d, s2: %s, ", p2, s2)
+		if lc.debug() {
+			lc.log("removeDistractionSuffix", "part", p2, "suffix", s2)
+		}
 		if ((s == "M") && (s2 == "P")) ||
 			((s == "P") && (s2 == "M")) ||
 			((s == "A") && (s2 == "M")) {
 			callparts2 := callparts[:p2]
-			DebugLogger.Printf("callparts: %#v\n", callparts2)
+			lc.addStep(ExplainStep{Step: StepDistractionSuffix, Call: s2 + "/" + s, Matched: true, Detail: "/M/P, /P/M, or /A/M"})
+			if lc.debug() {
+				lc.log("removeDistractionSuffix: removed", "callparts", strings.Join(callparts2, "/"))
+			}
 			return callparts2, true
 		}
 	}
 	// No removal
-	DebugLogger.Printf("no removal, callparts: %#v\n", callparts)
+	if lc.debug() {
+		lc.log("removeDistractionSuffix: no removal", "callparts", strings.Join(callparts, "/"))
+	}
 	return callparts, false
 }
 
 // Remove unnecessary distraction suffix recursively
-func removeDistractionSuffixes(callparts []string) []string {
+func (db *Database) removeDistractionSuffixes(callparts []string, lc *lookupContext) []string {
 	for {
-		callparts2, f := removeDistractionSuffix(callparts)
-		DebugLogger.Printf("removeDistractionSuffixes: removed: %t, partlength: %d, callparts: %s\n", f, len(callparts), callparts)
+		callparts2, f := db.removeDistractionSuffix(callparts, lc)
+		if lc.debug() {
+			lc.log("removeDistractionSuffixes", "removed", f, "partlength", len(callparts), "callparts", strings.Join(callparts, "/"))
+		}
 		if !f {
 			return callparts2
 		} else {
@@ -267,33 +253,40 @@ func removeDistractionSuffixes(callparts []string) []string {
 // Return prefix and suffix
 func splitCallsign(call string) (string, string) {
 	// Find prefix or prefix + suffix
-	prefixsuffix := regexp.MustCompile(`^([0-9]?[A-Z]+[0-9]+)([0-9A-Z]+)$`)
-	matches := prefixsuffix.FindStringSubmatch(call)
-	l := len(matches)
-	if l == 3 {
-		return matches[1], matches[2]
+	_, k, matched := scanCallArea(call)
+	if matched {
+		return call[:k], call[k:]
 	} else {
 		return "", ""
 	}
 }
 
-func checkException(call string, qsotime time.Time, oldresult CLDCheckResult) (CLDCheckResult, bool) { // Result value
+func (db *Database) checkException(call string, qsotime time.Time, oldresult CLDCheckResult, lc *lookupContext) (CLDCheckResult, bool) { // Result value
 	result := oldresult
 
 	// Check CLDMapException here
-	er, exists := inExceptionMap(call, qsotime)
+	er, overlay, exists := db.lookupException(call, qsotime, lc)
+	lc.addStep(ExplainStep{Step: StepException, Call: call, Record: er.Record, Matched: exists, Detail: overlayDetail(overlay)})
 	// If exists, return the result in the database
 	if exists {
 		result.Adif = er.Adif
 		result.Name = er.Entity
-		result.Prefix = CLDMapEntityByAdif[er.Adif].Prefix
+		result.Prefix = db.MapEntityByAdif[er.Adif].Prefix
 		result.Cqz = er.Cqz
 		result.Cont = er.Cont
 		result.Long = er.Long
 		result.Lat = er.Lat
-		result.Deleted = CLDMapEntityByAdif[er.Adif].Deleted
+		result.Deleted = db.MapEntityByAdif[er.Adif].Deleted
+		result.Status = StatusValid
+		result.Overlay = result.Overlay || overlay
+		result.ExceptionRecord = er.Record
+		result.MatchedKey = call
+		result.MatchedStart = er.Start
+		result.MatchedEnd = er.End
 		result.hasRecordException = true
-		DebugLogger.Printf("checkException: inExceptionMap result: %#v\n", er)
+		if lc.debug() {
+			lc.log("checkException: matched", "call", call, "record", er.Record, "adif", er.Adif)
+		}
 	} else {
 		result.hasRecordException = false
 	}
@@ -301,16 +294,21 @@ func checkException(call string, qsotime time.Time, oldresult CLDCheckResult) (C
 	return result, exists
 }
 
-func checkZoneException(call string, qsotime time.Time, oldresult CLDCheckResult) (CLDCheckResult, bool) {
+func (db *Database) checkZoneException(call string, qsotime time.Time, oldresult CLDCheckResult, lc *lookupContext) (CLDCheckResult, bool) {
 	// Result value
 	result := oldresult
 
 	// Check CLDZoneException here
-	zer, exists := inZoneExceptionMap(call, qsotime)
+	zer, overlay, exists := db.lookupZoneException(call, qsotime, lc)
+	lc.addStep(ExplainStep{Step: StepZoneException, Call: call, Record: zer.Record, Matched: exists, Detail: overlayDetail(overlay)})
 	if exists {
 		result.Cqz = zer.Zone
+		result.Overlay = result.Overlay || overlay
+		result.ZoneExceptionRecord = zer.Record
 		result.hasRecordZoneException = true
-		DebugLogger.Printf("checkZoneException: inZoneExceptionMap result: %#v\n", zer)
+		if lc.debug() {
+			lc.log("checkZoneException: matched", "call", call, "record", zer.Record, "zone", zer.Zone)
+		}
 	} else {
 		result.hasRecordZoneException = false
 	}
@@ -319,44 +317,93 @@ func checkZoneException(call string, qsotime time.Time, oldresult CLDCheckResult
 }
 
 // External API call function
-// Parse a callsign and time
+// Parse a callsign and time with the default Database
 // with given callsign and contact/QSO time
 // Note well: callsign must be uppercased
 func CheckCallsign(call string, qsotime time.Time) (CLDCheckResult, error) {
+	return defaultDatabase.Load().CheckCallsign(call, qsotime)
+}
+
+// Parse a callsign and time with the default Database
+// See (*Database).CheckCallsignContext()
+func CheckCallsignContext(ctx context.Context, call string, qsotime time.Time) (CLDCheckResult, error) {
+	return defaultDatabase.Load().CheckCallsignContext(ctx, call, qsotime)
+}
+
+// Parse a callsign and time with db
+// with given callsign and contact/QSO time
+// Note well: callsign must be uppercased
+func (db *Database) CheckCallsign(call string, qsotime time.Time) (CLDCheckResult, error) {
+	return db.checkCallsign(context.Background(), call, qsotime, nil)
+}
+
+// Parse a callsign and time with db
+// The debug messages are written with ctx
+// and the trace ID of ctx set by WithTraceID()
+// Note well: callsign must be uppercased
+func (db *Database) CheckCallsignContext(ctx context.Context, call string, qsotime time.Time) (CLDCheckResult, error) {
+	return db.checkCallsign(ctx, call, qsotime, nil)
+}
+
+// Parse a callsign and time with db
+// recording the lookup details into lc if not nil
+func (db *Database) checkCallsign(ctx context.Context, call string, qsotime time.Time, lc *lookupContext) (CLDCheckResult, error) {
+	lc = db.traceLookup(ctx, lc)
 	// Result value
 	result1 := initCLDCheckResult()
 
 	// Print Club Log Database version
-	DebugLogger.Printf("CLDVersionDateTime: %s\n", CLDVersionDateTime.Format(ClublogTimeLayout))
+	if lc.debug() {
+		lc.log("CheckCallsign", "call", call, "qsotime", qsotime, "version", db.VersionDateTime)
+	}
 
 	// Check if callsign consists of
 	// digits, capital letters, and slashes only
 	// from length 1 to 16 characters
-	regcallcheck := regexp.MustCompile(`^[0-9|A-Z|\/]{1,16}$`)
 	// If not, return with malformed callsign error
-	if !(regcallcheck.MatchString(call)) {
+	if !isCallsignChars(call) {
+		lc.addStep(ExplainStep{Step: StepMalformed, Call: call, Matched: true, Detail: "invalid characters or length"})
+		result1.Status = StatusMalformed
 		return result1, ErrMalformedCallsign
 	}
 
 	// Check CLDMapInvalid here
-	ir, exists := inInvalidMap(call, qsotime)
+	ir, iroverlay, exists := db.lookupInvalid(call, qsotime, lc)
+	lc.addStep(ExplainStep{Step: StepInvalid, Call: call, Record: ir.Record, Matched: exists, Detail: overlayDetail(iroverlay)})
 	// If exists, return as an DXCC-invalid callsign
 	if exists {
 		result1.Adif = 0
 		result1.Name = NameInvalid
 		result1.Invalid = true
+		result1.Status = StatusInvalid
+		result1.Overlay = iroverlay
+		result1.InvalidRecord = ir.Record
+		result1.MatchedKey = call
+		result1.MatchedStart = ir.Start
+		result1.MatchedEnd = ir.End
 		result1.hasRecordInvalid = true
-		DebugLogger.Printf("CheckCallsign: inInvalidMap result: %#v\n", ir)
+		if lc.debug() {
+			lc.log("CheckCallsign: invalid operation", "call", call, "record", ir.Record)
+		}
 
 		return result1, nil
 	}
 
+	// If the callsign does not contain slashes
+	// Use the processing function for zero-slash callsign
+	if strings.IndexByte(call, '/') < 0 {
+		return db.checkCallsignZeroSlash(call, qsotime, lc)
+	}
+
 	// Split callsign separated by "/" into parts
-	callparts := strings.Split(call, "/")
+	var partsbuf [CallsignMaxLength + 1]string
+	callparts := splitCallparts(partsbuf[:0], call)
 	// Check how many parts in the callparts
 	partlength := len(callparts)
 
-	DebugLogger.Printf("partlength: %d, callparts: %#v\n", partlength, callparts)
+	if lc.debug() {
+		lc.log("CheckCallsign: split", "partlength", partlength, "callparts", strings.Join(callparts, "/"))
+	}
 
 	// Check Aeronautical Mobile
 	// If any part in the callparts contains "AM"
@@ -367,8 +414,10 @@ func CheckCallsign(call string, qsotime time.Time) (CLDCheckResult, error) {
 				result1.Adif = 0
 				result1.Name = NameAeronauticalMobile
 				result1.Invalid = true
+				result1.Status = StatusAeronauticalMobile
 				result1.hasRecordInvalid = false
-				DebugLogger.Printf("CheckCallsign: Aeronautical Mobile\n")
+				lc.addStep(ExplainStep{Step: StepAeronauticalMobile, Call: s, Matched: true})
+				lc.log("CheckCallsign: Aeronautical Mobile")
 				return result1, nil
 			}
 		}
@@ -377,46 +426,44 @@ func CheckCallsign(call string, qsotime time.Time) (CLDCheckResult, error) {
 	// Check Maritime Mobile
 	// (If second or later part in the callparts contains "MM[0-9]?")
 	// exception: if the first part contains "MM[0-9]?", that is Scotland
-	mmcheck := regexp.MustCompile(`^MM[0-9]?$`)
 	for i := 1; i < partlength; i++ {
 		s := callparts[i]
-		if mmcheck.MatchString(s) {
+		if isMaritimeMobile(s) {
 			// Maritime Mobile Callsign
 			result1.Adif = 0
 			result1.Name = NameMaritimeMobile
 			result1.Invalid = true
+			result1.Status = StatusMaritimeMobile
 			result1.hasRecordInvalid = false
-			DebugLogger.Printf("CheckCallsign: Maritime Mobile\n")
+			lc.addStep(ExplainStep{Step: StepMaritimeMobile, Call: s, Matched: true})
+			lc.log("CheckCallsign: Maritime Mobile")
 			return result1, nil
 		}
 	}
 
-	// If the callsign does not contain slashes
-	// Use the processing function for zero-slash callsign
-	if partlength == 1 {
-		return checkCallsignZeroSlash(call, qsotime)
-	}
-
 	// If a zero-length string in a split part of a callsign is found,
 	// treat it as malformed and exit
 	for _, s := range callparts {
 		if len(s) == 0 {
+			lc.addStep(ExplainStep{Step: StepMalformed, Call: call, Matched: true, Detail: "empty part"})
+			result1.Status = StatusMalformed
 			return result1, ErrMalformedCallsign
 		}
 	}
 
 	// CLDMapException check
-	result2, found2 := checkException(call, qsotime, result1)
+	result2, found2 := db.checkException(call, qsotime, result1, lc)
 	if found2 {
-		return postCheckCallsign(call, qsotime, result2)
+		return db.postCheckCallsign(call, qsotime, result2, lc)
 	}
 	// If KL7/JJ1BDX form, also check with JJ1BDX/KL7
 	// for CLDMapException and CLDMapZoneException
 	if partlength == 2 {
 		callswapped := callparts[1] + "/" + callparts[0]
-		result3, found3 := checkException(callswapped, qsotime, result2)
+		lc.addStep(ExplainStep{Step: StepSwapped, Call: call, NewCall: callswapped})
+		result3, found3 := db.checkException(callswapped, qsotime, result2, lc)
 		if found3 {
-			return postCheckCallsign(call, qsotime, result3)
+			return db.postCheckCallsign(call, qsotime, result3, lc)
 		}
 	}
 
@@ -440,85 +487,74 @@ func CheckCallsign(call string, qsotime time.Time) (CLDCheckResult, error) {
 				if suffix != "" {
 					rp = callparts[0] + "/" + callparts[1]
 				} else {
+					lc.addStep(ExplainStep{Step: StepMalformed, Call: call, Matched: true, Detail: "no full callsign in three parts"})
+					result2.Status = StatusMalformed
 					return result2, ErrMalformedCallsign
 				}
 			}
 		}
-		DebugLogger.Printf("rp = %s, prefix = %s, suffix = %s\n", rp, prefix, suffix)
+		if lc.debug() {
+			lc.log("CheckCallsign: reference prefix", "rp", rp, "prefix", prefix, "suffix", suffix)
+		}
 
-		// special rules for 3D2, FO, FR are covered with inPrefixMap
+		lc.addStep(ExplainStep{Step: StepReferencePrefix, Call: call, NewCall: rp, Matched: true})
 
-		// SPECIAL RULE: JD/M and JD/O
-		// SPECIAL RULE: Minami Torishima
-		if rp == "JD/M" {
-			rp = "JD1M"
-		}
-		// SPECIAL RULE: Ogasawara
-		if rp == "JD/O" {
-			rp = "JD1"
-		}
-		// SPECIAL RULE: HK0/M for Malpelo
-		if rp == "HK0/M" {
-			rp = "HK0M"
-		}
-		// SPECIAL RULE: ZK1/S
-		if rp == "ZK1/S" {
-			rp = "ZK1"
-		}
-		// SPECIAL RULE: E5/S
-		if rp == "E5/S" {
-			rp = "E5"
-		}
+		// SPECIAL RULES: JD/M, JD/O, HK0/M, ZK1/S, E5/S
+		// See builtinSpecialRules
+		var v ruleValues
+		v[ruleValueRP] = rp
+		db.specialRules().apply(ruleStageThreePart, &v, qsotime, lc)
+		rp = v[ruleValueRP]
 
-		DebugLogger.Printf("rp after rewrite: %s\n", rp)
+		if lc.debug() {
+			lc.log("CheckCallsign: after rewrite", "rp", rp)
+		}
 		var mp string
 		var mpm CLDPrefix
-		var found bool
+		var overlay, found bool
 		// Prefix lookup
-		mp, mpm, found = inPrefixMap(rp, qsotime)
-		DebugLogger.Printf("mp: %s, mpm: %#v, found: %t\n", mp, mpm, found)
+		mp, mpm, overlay, found = db.lookupPrefix(rp, qsotime, lc)
+		if lc.debug() {
+			lc.log("prefix lookup", "prefix", mp, "record", mpm.Record, "adif", mpm.Adif, "found", found)
+		}
 
-		adif := mpm.Adif
-		result2.Adif = adif
-		result2.Name = mpm.Entity
-		result2.Prefix = mp
-		result2.Cqz = mpm.Cqz
-		result2.Cont = mpm.Cont
-		result2.Long = mpm.Long
-		result2.Lat = mpm.Lat
-		result2.Deleted = CLDMapEntityByAdif[adif].Deleted
+		db.setPrefixResult(&result2, mp, mpm, overlay)
 
-		return postCheckCallsign(call, qsotime, result2)
+		return db.postCheckCallsign(call, qsotime, result2, lc)
 	}
 
 	// Remove Distraction Suffixes
-	callparts2 := removeDistractionSuffixes(callparts)
+	callparts2 := db.removeDistractionSuffixes(callparts, lc)
 	partlength2 := len(callparts2)
-	DebugLogger.Printf("truncated callparts: partlength: %d, callparts: %s\n", partlength2, callparts2)
+	if lc.debug() {
+		lc.log("CheckCallsign: truncated", "partlength", partlength2, "callparts", strings.Join(callparts2, "/"))
+	}
 
 	// Rebuild reduced callsign from callparts
 	if partlength2 == 0 {
+		lc.addStep(ExplainStep{Step: StepMalformed, Call: call, Matched: true, Detail: "no part left"})
+		result1.Status = StatusMalformed
 		return result1, ErrMalformedCallsign
 	}
-	call2 := ""
-	for i := 0; i < (partlength2 - 1); i++ {
-		call2 = call2 + callparts2[i] + "/"
+	// callparts2 is the leading parts of call
+	call2 := call[:callpartsLength(callparts2)]
+	if lc.debug() {
+		lc.log("CheckCallsign: rebuilt", "call", call2)
 	}
-	call2 = call2 + callparts2[partlength2-1]
-	DebugLogger.Printf("rebuilt callsign: %s\n", call2)
 
 	// CLDMapException check for the rebuilt callsign again
-	result3, found3 := checkException(call2, qsotime, result1)
+	result3, found3 := db.checkException(call2, qsotime, result1, lc)
 	if found3 {
-		return postCheckCallsign(call2, qsotime, result3)
+		return db.postCheckCallsign(call2, qsotime, result3, lc)
 	}
 	// If KL7/JJ1BDX form, also check with JJ1BDX/KL7
 	// for CLDMapException and CLDMapZoneException
 	if partlength2 == 2 {
 		callswapped2 := callparts2[1] + "/" + callparts2[0]
-		result3, found3 := checkException(callswapped2, qsotime, result1)
+		lc.addStep(ExplainStep{Step: StepSwapped, Call: call2, NewCall: callswapped2})
+		result3, found3 := db.checkException(callswapped2, qsotime, result1, lc)
 		if found3 {
-			return postCheckCallsign(call2, qsotime, result3)
+			return db.postCheckCallsign(call2, qsotime, result3, lc)
 		}
 	}
 
@@ -528,47 +564,34 @@ func CheckCallsign(call string, qsotime time.Time) (CLDCheckResult, error) {
 	if partlength2 == 2 {
 		ls := callparts2[1]
 		rd := ""
-		if (len(ls) == 1) && unicode.IsDigit(rune(ls[0])) {
+		if (len(ls) == 1) && isDigit(ls[0]) {
 			rd = ls
 			// Assume the first part is a full callsign
-			prefixnumsuffix := regexp.MustCompile(`^([0-9]?[A-Z]+)([0-9]+)([0-9A-Z]+)$`)
-			matches := prefixnumsuffix.FindStringSubmatch(callparts2[0])
-			if len(matches) < 4 {
+			j, k, matched := scanCallArea(callparts2[0])
+			if !matched {
+				lc.addStep(ExplainStep{Step: StepMalformed, Call: callparts2[0], Matched: true, Detail: "no call area"})
+				result1.Status = StatusMalformed
 				return result1, ErrMalformedCallsign
 			}
-			newprefix := matches[1]
-			newcallarea := rd
-			newsuffix := matches[3]
-
-			// SPECIAL RULE: US prefix rules
-			usprefix := regexp.MustCompile(`^[KNW][A-Z]{0,1}$|^A[A-L]$`)
-			if usprefix.MatchString(newprefix) {
-				newprefix = "K"
-			}
-
-			// SPECIAL RULE: BS/7 -> BS0 (CHINA), not BS7
-			if (newprefix == "BS") && (newcallarea == "7") {
-				newcallarea = "0"
-			}
-
-			// SPECIAL RULE: Russian prefix/9:
-			// add "V" to the top of the suffix
-			// so that UA9AA/9 -> UA9VAA, RU9I/9 -> RU9VI
-			// (to Zone 18)
-			if ((newprefix[0] == 'R') || (newprefix[0] == 'U')) &&
-				(newcallarea == "9") {
-				newsuffix = "V" + newsuffix
-			}
-
-			newcall := newprefix + newcallarea + newsuffix
-			return checkCallsignZeroSlash(newcall, qsotime)
+			var v ruleValues
+			v[ruleValuePrefix] = callparts2[0][:j]
+			v[ruleValueArea] = rd
+			v[ruleValueSuffix] = callparts2[0][k:]
+
+			// SPECIAL RULES: US prefix, BS/7, Russian prefix/9
+			// See builtinSpecialRules
+			db.specialRules().apply(ruleStageCallArea, &v, qsotime, lc)
+
+			newcall := v[ruleValuePrefix] + v[ruleValueArea] + v[ruleValueSuffix]
+			lc.addStep(ExplainStep{Step: StepCallArea, Call: call2, NewCall: newcall, Matched: true})
+			return db.checkCallsignZeroSlash(newcall, qsotime, lc)
 		}
 	}
 
 	// If the callsign does not contain slashes
 	// Use the processing function for zero-slash callsign
 	if partlength2 == 1 {
-		return checkCallsignZeroSlash(call2, qsotime)
+		return db.checkCallsignZeroSlash(call2, qsotime, lc)
 	}
 
 	// Use the first two parts of split callsign
@@ -578,9 +601,13 @@ func CheckCallsign(call string, qsotime time.Time) (CLDCheckResult, error) {
 	rp := ""
 
 	prefix1, suffix1 := splitCallsign(callparts2[0])
-	DebugLogger.Printf("prefix1: %s, suffix1: %s\n", prefix1, suffix1)
+	if lc.debug() {
+		lc.log("CheckCallsign: first part", "prefix", prefix1, "suffix", suffix1)
+	}
 	prefix2, suffix2 := splitCallsign(callparts2[1])
-	DebugLogger.Printf("prefix2: %s, suffix2: %s\n", prefix2, suffix2)
+	if lc.debug() {
+		lc.log("CheckCallsign: second part", "prefix", prefix2, "suffix", suffix2)
+	}
 
 	// prefix-only (true) or full callsign (false)
 	isprefix1 := len(suffix1) == 0
@@ -598,10 +625,6 @@ func CheckCallsign(call string, qsotime time.Time) (CLDCheckResult, error) {
 	} else if isprefix2 {
 		// JJ1BDX/KL7
 		rp = callparts2[1]
-		// SPECIAL RULE: Ignore /M or /N suffixes: use first part
-		if (rp == "M") || (rp == "N") {
-			rp = callparts2[0]
-		}
 	} else {
 		// JJ1BDX/N6BDX
 		if len(callparts2[0]) <= len(callparts2[1]) {
@@ -610,139 +633,114 @@ func CheckCallsign(call string, qsotime time.Time) (CLDCheckResult, error) {
 			rp = callparts2[1]
 		}
 	}
-	DebugLogger.Printf("rp: %s\n", rp)
-
-	// SPECIAL RULE: TK/2A and TK/2B is CORSICA
-	if strings.HasPrefix(prefix1, "TK") &&
-		((callparts2[1] == "2A") || (callparts2[1] == "2B")) {
-		rp = "TK"
-	}
-
-	// SPECIAL RULE: 3D2 with /C or /S
-	if strings.HasPrefix(prefix1, "3D2") {
-		rp = "3D2/" + callparts2[1]
-	}
-
-	// SPECIAL RULE: FO with /A, /C, /M
-	if strings.HasPrefix(prefix1, "FO") {
-		rp = "FO/" + callparts2[1]
+	if lc.debug() {
+		lc.log("CheckCallsign: reference prefix", "rp", rp)
 	}
+	lc.addStep(ExplainStep{Step: StepReferencePrefix, Call: call2, NewCall: rp, Matched: true})
 
-	// SPECIAL RULE: FR with /E, /G, /J, /T
-	if strings.HasPrefix(prefix1, "FR") {
-		rp = "FR/" + callparts2[1]
-	}
+	// SPECIAL RULES: /M or /N, TK/2A, TK/2B, 3D2, FO, FR, HK0/M,
+	// ZK1/N, ZK1/S, E5/N, E5/S, IS, IM, KC4
+	// See builtinSpecialRules
+	var v ruleValues
+	v[ruleValueRP] = rp
+	v[ruleValuePrefix1] = prefix1
+	v[ruleValuePart1] = callparts2[0]
+	v[ruleValuePart2] = callparts2[1]
+	db.specialRules().apply(ruleStageTwoPart, &v, qsotime, lc)
+	rp = v[ruleValueRP]
 
-	// SPECIAL RULE: HK0/M -> HK0M
-	if strings.HasPrefix(prefix1, "HK0") {
-		rp = "HK0" + callparts2[1]
+	if lc.debug() {
+		lc.log("CheckCallsign: after rewrite", "rp", rp)
 	}
 
-	// SPECIAL RULE: ZK1/N and ZK1/S
-	if strings.HasPrefix(prefix1, "ZK1") {
-		if callparts2[1] == "N" {
-			// North Cook Islands ZK1/N
-			rp = "ZK1/N"
-		} else {
-			// South Cook Islands ZK1
-			rp = "ZK1"
-		}
-	}
-	// SPECIAL RULE: E5/N and E5/S
-	if strings.HasPrefix(prefix1, "E5") {
-		if callparts2[1] == "N" {
-			// North Cook Islands E5/N
-			rp = "E5/N"
-		} else {
-			// South Cook Islands E5
-			rp = "E5"
-		}
-	}
-
-	// SPECIAL RULE: Sardinia: IS -> IS0, IM -> IM0
-	if rp == "IS" {
-		rp = "IS0"
-	}
-	if rp == "IM" {
-		rp = "IM0"
-	}
-
-	// SPECIAL RULE: Antarctica: KC4 -> CE9
-	if rp == "KC4" {
-		rp = "CE9"
-	}
-
-	DebugLogger.Printf("rp after rewrite: %s\n", rp)
-
 	var mp string
 	var mpm CLDPrefix
-	var found bool
+	var overlay, found bool
 	// Prefix lookup
-	mp, mpm, found = inPrefixMap(rp, qsotime)
-	DebugLogger.Printf("mp: %s, mpm: %#v, found: %t\n", mp, mpm, found)
+	mp, mpm, overlay, found = db.lookupPrefix(rp, qsotime, lc)
+	if lc.debug() {
+		lc.log("prefix lookup", "prefix", mp, "record", mpm.Record, "adif", mpm.Adif, "found", found)
+	}
 
-	adif := mpm.Adif
-	result1.Adif = adif
-	result1.Name = mpm.Entity
-	result1.Prefix = mp
-	result1.Cqz = mpm.Cqz
-	result1.Cont = mpm.Cont
-	result1.Long = mpm.Long
-	result1.Lat = mpm.Lat
-	result1.Deleted = CLDMapEntityByAdif[adif].Deleted
-
-	return postCheckCallsign(call2, qsotime, result1)
+	db.setPrefixResult(&result1, mp, mpm, overlay)
+
+	return db.postCheckCallsign(call2, qsotime, result1, lc)
 }
 
 // Parse a callsign (assuming without slash) and time
 // with given callsign and contact/QSO time
 // Note well: callsign must be uppercased
-func checkCallsignZeroSlash(call string, qsotime time.Time) (CLDCheckResult, error) {
+func (db *Database) checkCallsignZeroSlash(call string, qsotime time.Time, lc *lookupContext) (CLDCheckResult, error) {
 	// Result value
 	result1 := initCLDCheckResult()
 
 	// Check Exception database and if found use it
-	result2, found2 := checkException(call, qsotime, result1)
+	result2, found2 := db.checkException(call, qsotime, result1, lc)
 	if found2 {
-		return postCheckCallsign(call, qsotime, result2)
+		return db.postCheckCallsign(call, qsotime, result2, lc)
 	}
 
 	// Extract prefix from a callsign
 	prefix, suffix := splitCallsign(call)
-	DebugLogger.Printf("call: %s, prefix: %s, suffix: %s\n", call, prefix, suffix)
+	if lc.debug() {
+		lc.log("checkCallsignZeroSlash", "call", call, "prefix", prefix, "suffix", suffix)
+	}
 
 	// Find a longest valid prefix in the CLDMapPrefixNoSlash
-	mp, mpm, found := inPrefixMap(call, qsotime)
-	DebugLogger.Printf("mp: %s, mpm: %#v, found: %t\n", mp, mpm, found)
+	mp, mpm, overlay, found := db.lookupPrefix(call, qsotime, lc)
+	if lc.debug() {
+		lc.log("prefix lookup", "prefix", mp, "record", mpm.Record, "adif", mpm.Adif, "found", found)
+	}
 
 	// SPECIAL RULE: For KG4 prefix
-	// if suffix is 2-letter, then it remains Gitmo
-	// else, it's USA
-	if (mp == "KG4") && (len(suffix) != 2) {
-		mp, mpm, found = inPrefixMap("K", qsotime)
-		DebugLogger.Printf("KG4 prefix rewrite\n")
+	// See builtinSpecialRules
+	// Look up the rewritten prefix
+	var v ruleValues
+	v[ruleValuePrefix] = mp
+	v[ruleValueSuffix] = suffix
+	db.specialRules().apply(ruleStageZeroSlash, &v, qsotime, lc)
+	if v[ruleValuePrefix] != mp {
+		mp, mpm, overlay, found = db.lookupPrefix(v[ruleValuePrefix], qsotime, lc)
+	}
+
+	if lc.debug() {
+		lc.log("checkCallsignZeroSlash: after rewrite", "prefix", mp, "record", mpm.Record, "adif", mpm.Adif, "found", found)
 	}
 
-	DebugLogger.Printf("After rewrite: mp: %s, mpm: %#v, found: %t\n", mp, mpm, found)
+	db.setPrefixResult(&result1, mp, mpm, overlay)
 
+	return db.postCheckCallsign(call, qsotime, result1, lc)
+}
+
+// Set the result of the matched prefix
+// The fields are cleared and Status is StatusNotFound if not matched
+func (db *Database) setPrefixResult(result *CLDCheckResult, mp string, mpm CLDPrefix, overlay bool) {
 	adif := mpm.Adif
-	result1.Adif = adif
-	result1.Name = mpm.Entity
-	result1.Prefix = mp
-	result1.Cqz = mpm.Cqz
-	result1.Cont = mpm.Cont
-	result1.Long = mpm.Long
-	result1.Lat = mpm.Lat
-	result1.Deleted = CLDMapEntityByAdif[adif].Deleted
-
-	return postCheckCallsign(call, qsotime, result1)
+	result.Adif = adif
+	result.Name = mpm.Entity
+	result.Prefix = mp
+	result.Cqz = mpm.Cqz
+	result.Cont = mpm.Cont
+	result.Long = mpm.Long
+	result.Lat = mpm.Lat
+	result.Deleted = db.MapEntityByAdif[adif].Deleted
+	result.Overlay = overlay
+	if mp != "" {
+		result.Status = StatusValid
+	} else {
+		result.Status = StatusNotFound
+	}
+	result.PrefixRecord = mpm.Record
+	result.MatchedKey = mp
+	result.MatchedStart = mpm.Start
+	result.MatchedEnd = mpm.End
 }
 
 // Post-process Callsign check
-func postCheckCallsign(call string, qsotime time.Time, oldresult CLDCheckResult) (CLDCheckResult, error) {
+func (db *Database) postCheckCallsign(call string, qsotime time.Time, oldresult CLDCheckResult, lc *lookupContext) (CLDCheckResult, error) {
 
 	// CLDMapException check
-	result2, found2 := checkZoneException(call, qsotime, oldresult)
+	result2, found2 := db.checkZoneException(call, qsotime, oldresult, lc)
 
 	var result3 CLDCheckResult
 	if found2 {
@@ -751,17 +749,31 @@ func postCheckCallsign(call string, qsotime time.Time, oldresult CLDCheckResult)
 		result3 = oldresult
 	}
 
-	me := CLDMapEntityByAdif[result3.Adif]
+	me := db.MapEntityByAdif[result3.Adif]
 	// If whitelisted and within the time range of whitelist
 	// and if not in the Exception database,
 	// then the callsign is BLOCKED and invalidated by the whitelist
+	if me.Whitelist {
+		lc.narrow(qsotime, me.WhitelistStart, me.WhitelistEnd)
+	}
 	if me.Whitelist &&
 		timeInRange(qsotime, me.WhitelistStart, me.WhitelistEnd) &&
 		!result3.hasRecordException {
+		lc.addStep(ExplainStep{Step: StepWhitelist, Call: call, Record: uint64(result3.Adif), Matched: true, Detail: "blocked"})
 		result3.Adif = 0
 		result3.Name = NameInvalid
 		result3.BlockedByWhitelist = true
 		result3.Invalid = true
+		result3.Status = StatusBlockedByWhitelist
+	}
+
+	if lc.debug() {
+		lc.log("CheckCallsign: result", "call", call, "adif", result3.Adif, "status", result3.Status)
+	}
+
+	// No exception or prefix record matched
+	if result3.Status == StatusNotFound {
+		return result3, ErrNoMatch
 	}
 
 	return result3, nil
@@ -0,0 +1,148 @@
+package gocldb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Callsigns of the lookup benchmarks with the fixture
+// and the allocations per lookup
+// A callsign with slashes allocates the swapped or rewritten callsigns
+var benchCalls = []struct {
+	name   string
+	call   string
+	time   string
+	allocs float64
+}{
+	{"Plain", "JA1ABC", "2023-01-15T00:00:00Z", 0},
+	{"Exception", "JA1XYZ", "2019-01-15T00:00:00Z", 0},
+	{"Invalid", "JA1INV", "2022-06-01T00:00:00Z", 0},
+	{"Portable", "JA1ABC/P", "2023-01-15T00:00:00Z", 1},
+	{"CallArea", "W1AW/2", "2023-01-15T00:00:00Z", 3},
+	{"ThreePart", "KL7/JA1ABC/P", "2023-01-15T00:00:00Z", 1},
+	{"PrefixSlash", "KL7/JA1ABC", "2023-01-15T00:00:00Z", 2},
+	{"Malformed", "JA1AB-C", "2023-01-15T00:00:00Z", 0},
+}
+
+func TestCheckCallsignProvenance(t *testing.T) {
+	db := loadTestDatabase(t)
+	tests := []struct {
+		name                             string
+		call                             string
+		time                             string
+		adif                             uint16
+		exception, prefix, invalid, zone uint64
+		key                              string
+		start                            string
+		end                              string
+	}{
+		{"exception", "JA1XYZ", "2019-01-15T00:00:00Z", 177, 1, 0, 0, 0,
+			"JA1XYZ", "2019-01-01T00:00:00Z", "2019-01-31T23:59:59Z"},
+		{"exception with slash", "KL7/JJ1BDX", "2023-01-15T00:00:00Z", 6, 4, 0, 0, 0,
+			"KL7/JJ1BDX", "", ""},
+		{"prefix", "JA1ABC", "2023-01-15T00:00:00Z", 339, 0, 100, 0, 0,
+			"JA", "", ""},
+		{"prefix of portable", "JA1ABC/P", "2023-01-15T00:00:00Z", 339, 0, 100, 0, 0,
+			"JA", "", ""},
+		{"prefix with time range", "KL7ABC", "2000-06-01T00:00:00Z", 6, 0, 133, 0, 0,
+			"KL7", "2000-01-01T00:00:00Z", "2001-12-31T23:59:59Z"},
+		{"invalid", "JA1INV", "2022-06-01T00:00:00Z", 0, 0, 0, 200, 0,
+			"JA1INV", "2022-01-01T00:00:00Z", "2022-12-31T23:59:59Z"},
+		// The zone exception has no MatchedKey of its own
+		{"zone exception", "W1AW", "2023-01-15T00:00:00Z", 291, 0, 129, 0, 300,
+			"W", "", ""},
+		{"not found", "QQ1ABC", "2023-01-15T00:00:00Z", 0, 0, 0, 0, 0,
+			"", "", ""},
+	}
+	for _, tt := range tests {
+		result, _ := db.CheckCallsign(tt.call, mustTime(t, tt.time))
+		if result.Adif != tt.adif ||
+			result.ExceptionRecord != tt.exception || result.PrefixRecord != tt.prefix ||
+			result.InvalidRecord != tt.invalid || result.ZoneExceptionRecord != tt.zone {
+			t.Errorf("%s: CheckCallsign(%s) = adif %d records %d %d %d %d, want %d %d %d %d %d",
+				tt.name, tt.call, result.Adif,
+				result.ExceptionRecord, result.PrefixRecord, result.InvalidRecord, result.ZoneExceptionRecord,
+				tt.adif, tt.exception, tt.prefix, tt.invalid, tt.zone)
+		}
+		// Open ends of a matched record are minTime and maxTime
+		start, end := minTime, maxTime
+		if tt.key == "" {
+			start, end = time.Time{}, time.Time{}
+		}
+		if tt.start != "" {
+			start, end = mustTime(t, tt.start), mustTime(t, tt.end)
+		}
+		if result.MatchedKey != tt.key ||
+			!result.MatchedStart.Equal(start) || !result.MatchedEnd.Equal(end) {
+			t.Errorf("%s: CheckCallsign(%s) = matched %q %v %v, want %q %v %v",
+				tt.name, tt.call, result.MatchedKey, result.MatchedStart, result.MatchedEnd,
+				tt.key, start, end)
+		}
+	}
+}
+
+func TestCheckCallsignStatus(t *testing.T) {
+	db := loadTestDatabase(t)
+	tests := []struct {
+		call   string
+		time   string
+		status Status
+		adif   uint16
+		err    error
+	}{
+		{"JA1ABC", "2023-01-15T00:00:00Z", StatusValid, 339, nil},
+		{"JA1XYZ", "2019-01-15T00:00:00Z", StatusValid, 177, nil},
+		{"JA1INV", "2022-06-01T00:00:00Z", StatusInvalid, 0, nil},
+		{"BS7ABC", "2023-01-15T00:00:00Z", StatusBlockedByWhitelist, 0, nil},
+		{"JA1ABC/AM", "2023-01-15T00:00:00Z", StatusAeronauticalMobile, 0, nil},
+		{"JA1ABC/MM", "2023-01-15T00:00:00Z", StatusMaritimeMobile, 0, nil},
+		{"QQ1ABC", "2023-01-15T00:00:00Z", StatusNotFound, 0, ErrNoMatch},
+		{"JA1AB-C", "2023-01-15T00:00:00Z", StatusMalformed, 0, ErrMalformedCallsign},
+	}
+	for _, tt := range tests {
+		result, err := db.CheckCallsign(tt.call, mustTime(t, tt.time))
+		if result.Status != tt.status || result.Adif != tt.adif || !errors.Is(err, tt.err) {
+			t.Errorf("CheckCallsign(%s) = %v adif %d, %v, want %v adif %d, %v",
+				tt.call, result.Status, result.Adif, err, tt.status, tt.adif, tt.err)
+		}
+	}
+
+	// Not looked up if cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := db.CheckCallsigns(ctx, []Query{{Call: "JA1ABC", QsoTime: mustTime(t, "2023-01-15T00:00:00Z")}})
+	if results[0].Status != StatusUnknown || !errors.Is(results[0].Err, context.Canceled) {
+		t.Errorf("CheckCallsigns() cancelled = %v, %v, want %v", results[0].Status, results[0].Err, StatusUnknown)
+	}
+	if s := StatusMalformed + 1; s.String() != "Status(8)" {
+		t.Errorf("Status(8).String() = %q", s.String())
+	}
+}
+
+func TestCheckCallsignAllocs(t *testing.T) {
+	db := loadTestDatabase(t)
+	for _, bc := range benchCalls {
+		qsotime := mustTime(t, bc.time)
+		allocs := testing.AllocsPerRun(100, func() {
+			db.CheckCallsign(bc.call, qsotime)
+		})
+		if allocs != bc.allocs {
+			t.Errorf("%s: %v allocs, want %v", bc.call, allocs, bc.allocs)
+		}
+	}
+}
+
+func BenchmarkCheckCallsign(b *testing.B) {
+	db := loadTestDatabase(b)
+	for _, bc := range benchCalls {
+		qsotime := mustTime(b, bc.time)
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				db.CheckCallsign(bc.call, qsotime)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// gocldb compressed cty.xml handling
+
+package gocldb
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path"
+	"strings"
+)
+
+// Magic bytes of compressed file formats
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte{'P', 'K', 0x03, 0x04}
+)
+
+// Read all cty.xml contents from r
+// If r is gzip or zip compressed, decompress the contents
+// detected by the magic bytes
+// Returns ErrTooLarge if the (decompressed) contents
+// exceed MaxCtyXmlSize
+func readCtyXml(r io.Reader) ([]byte, error) {
+	xr, err := newCtyXmlReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return readLimited(xr)
+}
+
+// Returns a reader of cty.xml contents from r
+// If r is gzip or zip compressed, the reader decompresses the contents
+// detected by the magic bytes
+// The returned reader is NOT limited by MaxCtyXmlSize
+func newCtyXmlReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	// Error of Peek() is ignored here; short input is handled by the decoder
+	magic, _ := br.Peek(len(zipMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, zipMagic):
+		return openZipCtyXml(br)
+	default:
+		return br, nil
+	}
+}
+
+// Read all contents from r up to MaxCtyXmlSize
+// Returns ErrTooLarge if the contents exceed MaxCtyXmlSize
+func readLimited(r io.Reader) ([]byte, error) {
+	buf, err := io.ReadAll(io.LimitReader(r, MaxCtyXmlSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) > MaxCtyXmlSize {
+		return nil, ErrTooLarge
+	}
+	return buf, nil
+}
+
+// Open cty.xml in a zip archive
+// Use the entry named cty.xml if exists,
+// or the first entry with the suffix .xml
+func openZipCtyXml(r io.Reader) (io.Reader, error) {
+	// zip archive requires random access
+	archive, err := readLimited(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, err
+	}
+	var entry *zip.File
+	for _, f := range zr.File {
+		name := path.Base(f.Name)
+		if name == "cty.xml" {
+			entry = f
+			break
+		}
+		if entry == nil && strings.HasSuffix(name, ".xml") {
+			entry = f
+		}
+	}
+	if entry == nil {
+		return nil, ErrNotFound
+	}
+	// Closing the entry is not needed for the in-memory archive
+	return entry.Open()
+}
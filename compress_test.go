@@ -0,0 +1,130 @@
+package gocldb
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadCompressedCtyXml(t *testing.T) {
+	db := loadTestDatabase(t)
+	qsotime := mustTime(t, "2019-01-15T00:00:00Z")
+	for _, filename := range []string{"testdata/cty.xml.gz", "testdata/cty.xml.zip"} {
+		cdb := NewDatabase()
+		if err := cdb.LoadCtyXmlFile(filename); err != nil {
+			t.Errorf("%s: %v", filename, err)
+			continue
+		}
+		checkSameTables(t, cdb, db)
+		for _, call := range []string{"JA1XYZ", "JA1ABC", "W1AW/2", "KG4AB", "QQ1ABC"} {
+			r1, err1 := db.CheckCallsign(call, qsotime)
+			r2, err2 := cdb.CheckCallsign(call, qsotime)
+			if r1 != r2 || !errors.Is(err2, err1) {
+				t.Errorf("%s: %s: %+v %v, want %+v %v", filename, call, r2, err2, r1, err1)
+			}
+		}
+
+		// The updater reads the same contents
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		xml, err := readCtyXml(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: readCtyXml(): %v", filename, err)
+		}
+		plain, err := os.ReadFile(testCtyXml)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(xml, plain) {
+			t.Errorf("%s: readCtyXml() differs from %s", filename, testCtyXml)
+		}
+	}
+}
+
+// Returns a gzip-compressed cty.xml decompressed
+// to more than MaxCtyXmlSize bytes of spaces
+func gzipBomb(t *testing.T) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&b, gzip.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Write([]byte(`<clublog date="2024-01-10T12:00:00+00:00">`))
+	spaces := []byte(strings.Repeat(" ", 1<<20))
+	for n := 0; n <= MaxCtyXmlSize; n += len(spaces) {
+		zw.Write(spaces)
+	}
+	zw.Write([]byte(`</clublog>`))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestGzipBomb(t *testing.T) {
+	bomb := gzipBomb(t)
+	if len(bomb) > 1<<20 {
+		t.Fatalf("bomb of %d bytes", len(bomb))
+	}
+	err := NewDatabase().LoadCtyXmlReader(bytes.NewReader(bomb))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("LoadCtyXmlReader(): %v, want ErrTooLarge", err)
+	}
+	_, err = readCtyXml(bytes.NewReader(bomb))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("readCtyXml(): %v, want ErrTooLarge", err)
+	}
+}
+
+// Returns a zip archive of the files of names and contents
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for name, contents := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(contents))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestZipEntries(t *testing.T) {
+	plain, err := os.ReadFile(testCtyXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		files map[string]string
+		err   error
+	}{
+		{"no cty.xml", map[string]string{"README.txt": "no cty.xml here"}, ErrNotFound},
+		// Any .xml entry is used if no cty.xml exists
+		{"other xml", map[string]string{"README.txt": "", "dir/clublog.xml": string(plain)}, nil},
+		// cty.xml is preferred
+		{"cty.xml", map[string]string{"a.xml": "broken", "dir/cty.xml": string(plain)}, nil},
+	}
+	for _, tt := range tests {
+		db := NewDatabase()
+		err := db.LoadCtyXmlReader(bytes.NewReader(zipArchive(t, tt.files)))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.err)
+		}
+		if err == nil && len(db.MapPrefix) == 0 {
+			t.Errorf("%s: no prefix loaded", tt.name)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package gocldb
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Calls of the fixture and their expected DXCC entity codes
+var concurrentCalls = []struct {
+	call string
+	adif uint16
+}{
+	{"JA1ABC", 339},
+	{"JD1ABC", 192},
+	{"JD1MAB", 177},
+	{"W1AW/2", 291},
+	{"KG4ABC", 291},
+	{"KG4AB", 105},
+	{"JA1ABC/KC4", 13},
+	{"UA9AA/9", 15},
+	{"3D2AG/C", 489},
+}
+
+// Look up the calls with check until stop is closed
+func lookupUntil(t *testing.T, stop <-chan struct{}, check func(string, time.Time) (CLDCheckResult, error)) {
+	t.Helper()
+	qsotime := mustTime(t, "2023-01-15T00:00:00Z")
+	for {
+		for _, c := range concurrentCalls {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			r, err := check(c.call, qsotime)
+			if err != nil {
+				t.Errorf("%s: %v", c.call, err)
+				return
+			}
+			if r.Adif != c.adif {
+				t.Errorf("%s: Adif = %d, want %d", c.call, r.Adif, c.adif)
+				return
+			}
+		}
+	}
+}
+
+// Package-level lookups while the default Database is reloaded
+func TestConcurrentLoadCtyXml(t *testing.T) {
+	old := DefaultDatabase()
+	t.Cleanup(func() { SetDefaultDatabase(old) })
+	if err := LoadCtyXmlFile(testCtyXml); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(testCtyXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	var lookups sync.WaitGroup
+	for range 4 {
+		lookups.Add(1)
+		go func() {
+			defer lookups.Done()
+			lookupUntil(t, stop, CheckCallsign)
+		}()
+	}
+	var loaders sync.WaitGroup
+	for i := range 4 {
+		loaders.Add(1)
+		go func() {
+			defer loaders.Done()
+			for range 10 {
+				var err error
+				if i%2 == 0 {
+					err = LoadCtyXmlFile(testCtyXml)
+				} else {
+					err = LoadCtyXmlReader(bytes.NewReader(data))
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	loaders.Wait()
+	close(stop)
+	lookups.Wait()
+}
+
+// Lookups through a Watcher and the default Database published by it
+// while the file is reloaded and a callback changes the Watcher
+func TestConcurrentWatcher(t *testing.T) {
+	old := DefaultDatabase()
+	t.Cleanup(func() { SetDefaultDatabase(old) })
+	data, err := os.ReadFile(testCtyXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "cty.xml")
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWatcher(filename, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.PublishDefault()
+	overlay := NewDatabase()
+	var reloads int
+	w.OnReload(func(oldVersion, newVersion time.Time) {
+		// Must not deadlock
+		w.SetOverlay(overlay)
+		reloads++
+	})
+
+	stop := make(chan struct{})
+	var lookups sync.WaitGroup
+	for _, check := range []func(string, time.Time) (CLDCheckResult, error){
+		w.CheckCallsign, CheckCallsign,
+	} {
+		lookups.Add(1)
+		go func() {
+			defer lookups.Done()
+			lookupUntil(t, stop, check)
+		}()
+	}
+	modTime := time.Now()
+	for i := range 10 {
+		modTime = modTime.Add(time.Second)
+		if err := os.Chtimes(filename, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		reloaded, err := w.Check()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reloaded {
+			t.Fatalf("Check() #%d: not reloaded", i)
+		}
+		if DefaultDatabase() != w.Database() {
+			t.Fatalf("Check() #%d: default Database not published", i)
+		}
+		if w.Database().Overlay != overlay {
+			t.Fatalf("Check() #%d: overlay not kept", i)
+		}
+	}
+	close(stop)
+	lookups.Wait()
+	if reloads != 10 {
+		t.Errorf("reloads = %d, want 10", reloads)
+	}
+}
+
+// Batch lookups while the default Database is reloaded
+func TestConcurrentBatch(t *testing.T) {
+	old := DefaultDatabase()
+	t.Cleanup(func() { SetDefaultDatabase(old) })
+	if err := LoadCtyXmlFile(testCtyXml); err != nil {
+		t.Fatal(err)
+	}
+	qsotime := mustTime(t, "2023-01-15T00:00:00Z")
+	var queries []Query
+	for range 50 {
+		for _, c := range concurrentCalls {
+			queries = append(queries, Query{Call: c.call, QsoTime: qsotime})
+		}
+	}
+	check := func(results []Result) {
+		if len(results) != len(queries) {
+			t.Errorf("len(results) = %d, want %d", len(results), len(queries))
+			return
+		}
+		for i, r := range results {
+			want := concurrentCalls[i%len(concurrentCalls)]
+			if r.Err != nil || r.Adif != want.adif || r.Query.Call != want.call {
+				t.Errorf("results[%d] = %s %d %v, want %s %d",
+					i, r.Query.Call, r.Adif, r.Err, want.call, want.adif)
+				return
+			}
+		}
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for range 10 {
+			if err := LoadCtyXmlFile(testCtyXml); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 5 {
+			check(CheckCallsigns(ctx, queries))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		b := &Batch{Workers: 3}
+		for range 5 {
+			check(slices.Collect(b.All(ctx, slices.Values(queries))))
+		}
+	}()
+	wg.Wait()
+}
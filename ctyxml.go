@@ -12,10 +12,13 @@ package gocldb
 
 import (
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
-	"path"
+	"path/filepath"
 	"time"
 )
 
@@ -29,19 +32,87 @@ const (
 	ClublogTimeLayout = "2006-01-02T15:04:05-07:00"
 	// Maximum size readable for ctl.xml in bytes
 	// (current size: ~10M bytes)
+	// Applied to the decompressed size for compressed files
 	MaxCtyXmlSize = 50000000
 )
 
+// Errors
+var ErrNotFound = errors.New("cty.xml not found")
+var ErrTooLarge = errors.New("cty.xml too large")
+
+// Error of parsing cty.xml contents as XML
+type ParseError struct {
+	// Input byte offset where the error is detected
+	Offset int64
+	// Underlying error
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("cty.xml parse error at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Error of a malformed TimeString in cty.xml
+type TimeFormatError struct {
+	// Section name of the record, e.g., "exceptions"
+	Section string
+	// Record number (ADIF entity code for entities)
+	Record uint64
+	// Malformed time string
+	Value TimeString
+	// Underlying error
+	Err error
+}
+
+func (e *TimeFormatError) Error() string {
+	return fmt.Sprintf("cty.xml time format error in %s record %d: %q: %v",
+		e.Section, e.Record, e.Value, e.Err)
+}
+
+func (e *TimeFormatError) Unwrap() error {
+	return e.Err
+}
+
+// Parse TimeString to time.Time
+func ParseTimeString(ts TimeString) (time.Time, error) {
+	return time.Parse(ClublogTimeLayout, string(ts))
+}
+
 // Convert TimeString to time.Time
+// Exit the program if failed; use ParseTimeString() to handle the error
 func ConvertTimeString(ts TimeString) time.Time {
-	t, err := time.Parse(ClublogTimeLayout, string(ts))
+	t, err := ParseTimeString(ts)
 	if err != nil {
 		log.Fatalf("ConvertTimeString() error: %v", err)
 	}
 	return t
 }
 
+// Convert TimeString of a record field to time.Time
+// Returns def if the field is empty
+func convertTimeField(ts TimeString, def time.Time, section string, record uint64) (time.Time, error) {
+	if len(ts) == 0 {
+		return def, nil
+	}
+	t, err := ParseTimeString(ts)
+	if err != nil {
+		return time.Time{}, &TimeFormatError{
+			Section: section,
+			Record:  record,
+			Value:   ts,
+			Err:     err,
+		}
+	}
+	return t, nil
+}
+
 // XML nested elements begins here
+// The loader decodes each record element one by one
+// and does not use Clublog and the container structs
 type Clublog struct {
 	XMLName xml.Name   `xml:"clublog"`
 	Date    TimeString `xml:"date,attr"`
@@ -152,15 +223,6 @@ type ClublogZoneExceptions struct {
 
 // XML nested elements ends here
 
-// Global variables within the package
-// for handling raw XML-based structs
-var ctyXmlData Clublog
-var ctyXmlEntities []EntitiesEntity
-var ctyXmlExceptions []ExceptionsException
-var ctyXmlPrefixes []PrefixesPrefix
-var ctyXmlInvalids []InvalidOperationsInvalid
-var ctyXmlZoneExceptions []ZoneExceptionsZoneException
-
 // Prefix (string) is the map key
 type CLDEntity struct {
 	Adif           uint16
@@ -234,237 +296,507 @@ type CLDZoneException struct {
 	End    time.Time
 }
 
+// Global variables kept for compatibility,
+// pointing to the tables of the default Database
+// set by LoadCtyXml()
+// Use DefaultDatabase() instead if the default Database
+// may be reloaded concurrently
+
 // Entity by prefix, returning a slice
-var CLDMapEntity = make(map[string][]CLDEntity, 500)
+var CLDMapEntity map[string][]CLDEntity
 
 // Entity by adif (Entity code)
 // Each entity code maps to only one Entity
-var CLDMapEntityByAdif = make(map[uint16]CLDEntityByAdif, 500)
+var CLDMapEntityByAdif map[uint16]CLDEntityByAdif
 
 // Entity Exception status by callsign, returning a slice
-var CLDMapException = make(map[string][]CLDException, 50000)
+var CLDMapException map[string][]CLDException
 
 // Entity by longest-match prefixes, returning a slice
-var CLDMapPrefix = make(map[string][]CLDPrefix, 10000)
+var CLDMapPrefix map[string][]CLDPrefix
 
 // DXCC-invalid status by callsign, returning a slice
-var CLDMapInvalid = make(map[string][]CLDInvalid, 10000)
+var CLDMapInvalid map[string][]CLDInvalid
 
 // Zone exception by callsign, returning a slice
-var CLDMapZoneException = make(map[string][]CLDZoneException, 10000)
+var CLDMapZoneException map[string][]CLDZoneException
 
 // Club Log Database release date and time
 var CLDVersionDateTime time.Time
 
+// True if the database is loaded from the embedded copy
+var CLDEmbedded bool
+
 // Logger for debug messages in this package
-var DebugLogger *log.Logger
+// Written by the Databases created without SetLogger();
+// this package never replaces it, so SetOutput() before loading
+// is kept by the loaders
+//
+// Deprecated: use SetLogger() or WithLogger() instead
+var DebugLogger = log.New(io.Discard, "gocldb-debug ",
+	log.Ldate|log.Ltime|log.LUTC)
+
+// Locate cty.xml and load it into a new Database,
+// then publish it as the default Database
+// and set the compatibility global variables.
+// Exit the program if failed; use LoadCtyXmlFile() to handle the error
+// Search the given list of file paths,
+// or CtyXmlSearchPath() if no path is given
+func LoadCtyXml(filenames ...string) {
+	db := NewDatabase()
+	err := db.LoadCtyXml(filenames...)
+	if err != nil {
+		log.Fatalf("LoadCtyXml(): %v", err)
+	}
+	SetDefaultDatabase(db)
+}
+
+// Load cty.xml of the given path into a new Database,
+// then publish it as the default Database
+// and set the compatibility global variables.
+// The default Database is unchanged if failed.
+// Safe to call while other goroutines search the default Database.
+func LoadCtyXmlFile(filename string) error {
+	db := NewDatabase()
+	err := db.LoadCtyXmlFile(filename)
+	if err != nil {
+		return err
+	}
+	SetDefaultDatabase(db)
+	return nil
+}
+
+// Load cty.xml contents from r into a new Database,
+// then publish it as the default Database
+// and set the compatibility global variables.
+// The default Database is unchanged if failed.
+// Safe to call while other goroutines search the default Database.
+func LoadCtyXmlReader(r io.Reader) error {
+	db := NewDatabase()
+	err := db.LoadCtyXmlReader(r)
+	if err != nil {
+		return err
+	}
+	SetDefaultDatabase(db)
+	return nil
+}
+
+// Environment variable name for the path of cty.xml
+const CtyXmlEnv = "GOCLDB_CTYXML"
 
-// Locate cty.xml and open the file,
-// then read all the contents.
-// Set CtyXmlData global variable with the database contents.
-// Set default logger to stderr.
+// Returns the default search path of cty.xml
 //
-// Search path:
+// Search path (in this order):
 //
-//	/usr/local/share/dxcc
-//	and the path where the program resides.
-func LoadCtyXml() {
-	// Set logger for debugging output, to discard as default
-	DebugLogger = log.New(io.Discard, "gocldb-debug ", log.Ldate|log.Ltime|log.LUTC|log.Lshortfile)
-
-	// Set basedir here
-	basename, err := os.Executable()
+//	$GOCLDB_CTYXML (file path, if set)
+//	$XDG_DATA_HOME/dxcc/cty.xml (if set)
+//	$HOME/.local/share/dxcc/cty.xml
+//	/usr/local/share/dxcc/cty.xml
+//	/usr/share/dxcc/cty.xml
+//	(directory where the executable file resides)/cty.xml
+//	(current directory)/cty.xml
+func CtyXmlSearchPath() []string {
+	filenames := make([]string, 0, 7)
+	if env := os.Getenv(CtyXmlEnv); env != "" {
+		filenames = append(filenames, env)
+	}
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		filenames = append(filenames, filepath.Join(xdg, "dxcc", "cty.xml"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		filenames = append(filenames,
+			filepath.Join(home, ".local", "share", "dxcc", "cty.xml"))
+	}
+	filenames = append(filenames,
+		"/usr/local/share/dxcc/cty.xml",
+		"/usr/share/dxcc/cty.xml")
+	if basename, err := os.Executable(); err == nil {
+		filenames = append(filenames, filepath.Join(filepath.Dir(basename), "cty.xml"))
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		filenames = append(filenames, filepath.Join(cwd, "cty.xml"))
+	}
+	return filenames
+}
+
+// Locate cty.xml from the given list of file paths,
+// or from CtyXmlSearchPath() if no path is given
+// Returns the first existing path, or ErrNotFound
+func FindCtyXml(filenames ...string) (string, error) {
+	if len(filenames) == 0 {
+		filenames = CtyXmlSearchPath()
+	}
+	for _, filename := range filenames {
+		fi, err := os.Stat(filename)
+		if err == nil && !fi.IsDir() {
+			return filename, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// Locate cty.xml from the given list of file paths,
+// or from CtyXmlSearchPath() if no path is given,
+// and load it into db
+// The path of the loaded file is set to db.Source
+// If not found in CtyXmlSearchPath(), the embedded copy is loaded if exists;
+// the given paths do not fall back to the embedded copy
+func (db *Database) LoadCtyXml(filenames ...string) error {
+	filename, err := FindCtyXml(filenames...)
 	if err != nil {
-		log.Fatalf("LoadCtyXml() basename: %v", err)
-	}
-	basedir := path.Dir(basename)
-
-	var filename string
-	filename = "/usr/local/share/dxcc/cty.xml"
-	_, err = os.Stat(filename)
-	if !os.IsNotExist(err) {
-	} else {
-		DebugLogger.Printf("LoadCtyXml(): %s does not exist\n", filename)
-		filename = basedir + "/cty.xml"
-		_, err = os.Stat(filename)
-		if !os.IsNotExist(err) {
-		} else {
-			log.Fatalf("LoadCtyXml() unable to find cty.xml: %v",
-				err)
+		if len(filenames) == 0 {
+			return db.fallbackEmbedded(err)
 		}
+		return err
 	}
+	db.Logger().Debug("LoadCtyXml(): found", "file", filename)
+	return db.LoadCtyXmlFile(filename)
+}
 
+// Open cty.xml of the given path and load it into db
+// Returns ErrNotFound wrapped with the cause if the file does not exist
+func (db *Database) LoadCtyXmlFile(filename string) error {
 	fp, err := os.Open(filename)
 	if err != nil {
-		log.Fatalf("LoadCtyXml() unable to open %s: %v", filename, err)
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return err
 	}
-	buf, err := io.ReadAll(io.LimitReader(fp, MaxCtyXmlSize))
+	err = db.LoadCtyXmlReader(fp)
+	cerr := fp.Close()
 	if err != nil {
-		log.Fatalf("LoadCtyXml() unable to io.ReadAll(): %v", err)
+		return err
+	}
+	if cerr != nil {
+		return cerr
 	}
-	err = fp.Close()
+	db.Source = filename
+	return nil
+}
+
+// Read cty.xml contents from r and load it into db
+// gzip or zip compressed contents are decompressed
+// The contents are decoded element by element
+// without keeping the raw XML-based structs
+// The tables of db are replaced with freshly built ones
+// only if succeeded, so loading twice does not duplicate the records
+// Do not load into a Database used by other goroutines;
+// load into a new Database and publish it instead
+func (db *Database) LoadCtyXmlReader(r io.Reader) error {
+	xr, err := newCtyXmlReader(r)
 	if err != nil {
-		log.Fatalf("LoadCtyXml() unable to close: %v", err)
+		return err
+	}
+	decoder := xml.NewDecoder(&maxSizeReader{r: xr, n: MaxCtyXmlSize})
+
+	// Build the new tables
+	nd := NewDatabase()
+	err = nd.decodeCtyXml(decoder)
+	if err != nil {
+		return err
+	}
+
+	db.replaceTables(nd)
+
+	return nil
+}
+
+// Reader returning ErrTooLarge after reading n bytes
+type maxSizeReader struct {
+	r io.Reader
+	n int64
+}
+
+func (m *maxSizeReader) Read(p []byte) (int, error) {
+	if m.n <= 0 {
+		// Check if any more byte exists
+		var b [1]byte
+		n, err := m.r.Read(b[:])
+		if n > 0 {
+			return 0, ErrTooLarge
+		}
+		return 0, err
 	}
-	err = xml.Unmarshal(buf, &ctyXmlData)
+	if int64(len(p)) > m.n {
+		p = p[:m.n]
+	}
+	n, err := m.r.Read(p)
+	m.n -= int64(n)
+	return n, err
+}
+
+// Read the release date and time of cty.xml from r
+// (the date attribute of the clublog element)
+// without reading the whole contents
+// gzip or zip compressed contents are decompressed
+func ReadCtyXmlDate(r io.Reader) (time.Time, error) {
+	xr, err := newCtyXmlReader(r)
 	if err != nil {
-		log.Fatalf("LoadCtyXml() unable to xml.Unmarshal() of size %d: %v", len(buf), err)
-	}
-
-	ctyXmlEntities = ctyXmlData.Entities.Entity
-	ctyXmlExceptions = ctyXmlData.Exceptions.Exception
-	ctyXmlPrefixes = ctyXmlData.Prefixes.Prefix
-	ctyXmlInvalids = ctyXmlData.InvalidOperations.Invalid
-	ctyXmlZoneExceptions = ctyXmlData.ZoneExceptions.ZoneException
-
-	// minimum and maximum time values
-	minTime := ConvertTimeString(TimeString("0001-01-01T00:00:00+00:00"))
-	maxTime := ConvertTimeString(TimeString("9999-12-31T23:59:59+00:00"))
-
-	CLDVersionDateTime = ConvertTimeString(ctyXmlData.Date)
-
-	for _, s := range ctyXmlEntities {
-		var d CLDEntity
-		var da CLDEntityByAdif
-
-		adif := s.Adif
-		prefix := s.Prefix
-
-		d.Adif = adif
-		d.Name = s.Name
-		d.Deleted = s.Deleted
-		d.Cqz = s.Cqz
-		d.Long = s.Long
-		d.Lat = s.Lat
-		if len(s.Start) > 0 {
-			d.Start = ConvertTimeString(s.Start)
-		} else {
-			d.Start = minTime
+		return time.Time{}, err
+	}
+	decoder := xml.NewDecoder(io.LimitReader(xr, MaxCtyXmlSize))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return time.Time{}, &ParseError{Offset: decoder.InputOffset(), Err: err}
+		}
+		se, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var date TimeString
+		for _, attr := range se.Attr {
+			if attr.Name.Local == "date" {
+				date = TimeString(attr.Value)
+			}
 		}
-		if len(s.End) > 0 {
-			d.End = ConvertTimeString(s.End)
-		} else {
-			d.End = maxTime
+		t, err := ParseTimeString(date)
+		if err != nil {
+			return time.Time{}, &TimeFormatError{Section: se.Name.Local, Value: date, Err: err}
 		}
-		d.Whitelist = s.Whitelist
-		if len(s.WhitelistStart) > 0 {
-			d.WhitelistStart = ConvertTimeString(s.WhitelistStart)
-		} else {
-			d.WhitelistStart = minTime
+		return t, nil
+	}
+}
+
+// Read the release date and time of the cty.xml file of filename
+// See ReadCtyXmlDate()
+func ReadCtyXmlFileDate(filename string) (time.Time, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer fp.Close()
+	return ReadCtyXmlDate(fp)
+}
+
+// minimum and maximum time values
+var minTime = ConvertTimeString(TimeString("0001-01-01T00:00:00+00:00"))
+var maxTime = ConvertTimeString(TimeString("9999-12-31T23:59:59+00:00"))
+
+// Decode cty.xml contents element by element from decoder
+// and set the maps of db
+func (db *Database) decodeCtyXml(decoder *xml.Decoder) error {
+	// Wrap the decoder error with the input offset
+	parseError := func(err error) error {
+		if errors.Is(err, ErrTooLarge) {
+			return ErrTooLarge
 		}
-		if len(s.WhitelistEnd) > 0 {
-			d.WhitelistEnd = ConvertTimeString(s.WhitelistEnd)
-		} else {
-			d.WhitelistEnd = maxTime
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
 		}
+		return &ParseError{Offset: decoder.InputOffset(), Err: err}
+	}
 
-		CLDMapEntity[prefix] = append(CLDMapEntity[prefix], d)
-
-		da.Name = d.Name
-		da.Prefix = prefix
-		da.Deleted = d.Deleted
-		da.Cqz = d.Cqz
-		da.Long = d.Long
-		da.Lat = d.Lat
-		da.Start = d.Start
-		da.End = d.End
-		da.Whitelist = d.Whitelist
-		da.WhitelistStart = d.WhitelistStart
-		da.WhitelistEnd = d.WhitelistEnd
-
-		// Here simple assignment, NOT appending
-		CLDMapEntityByAdif[adif] = da
-	}
-
-	for _, s := range ctyXmlExceptions {
-		var d CLDException
-
-		d.Record = s.Record
-		call := s.Call
-		d.Entity = s.Entity
-		d.Adif = s.Adif
-		d.Cqz = s.Cqz
-		d.Cont = s.Cont
-		d.Long = s.Long
-		d.Lat = s.Lat
-		if len(s.Start) > 0 {
-			d.Start = ConvertTimeString(s.Start)
-		} else {
-			d.Start = minTime
+	foundRoot := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF && foundRoot {
+			return nil
+		}
+		if err != nil {
+			return parseError(err)
+		}
+		se, ok := token.(xml.StartElement)
+		if !ok {
+			continue
 		}
-		if len(s.End) > 0 {
-			d.End = ConvertTimeString(s.End)
-		} else {
-			d.End = maxTime
+		switch se.Name.Local {
+		case "clublog":
+			var date TimeString
+			for _, attr := range se.Attr {
+				if attr.Name.Local == "date" {
+					date = TimeString(attr.Value)
+				}
+			}
+			db.VersionDateTime, err = ParseTimeString(date)
+			if err != nil {
+				return &TimeFormatError{Section: "clublog", Value: date, Err: err}
+			}
+			foundRoot = true
+		case "entity":
+			var s EntitiesEntity
+			err = decoder.DecodeElement(&s, &se)
+			if err != nil {
+				return parseError(err)
+			}
+			err = db.addEntity(&s)
+		case "exception":
+			var s ExceptionsException
+			err = decoder.DecodeElement(&s, &se)
+			if err != nil {
+				return parseError(err)
+			}
+			err = db.addException(&s)
+		case "prefix":
+			var s PrefixesPrefix
+			err = decoder.DecodeElement(&s, &se)
+			if err != nil {
+				return parseError(err)
+			}
+			err = db.addPrefix(&s)
+		case "invalid":
+			var s InvalidOperationsInvalid
+			err = decoder.DecodeElement(&s, &se)
+			if err != nil {
+				return parseError(err)
+			}
+			err = db.addInvalid(&s)
+		case "zone_exception":
+			var s ZoneExceptionsZoneException
+			err = decoder.DecodeElement(&s, &se)
+			if err != nil {
+				return parseError(err)
+			}
+			err = db.addZoneException(&s)
 		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Convert the start and end time fields of a record
+// Empty start and end are converted to minTime and maxTime
+func convertTimeRange(start, end TimeString, section string, record uint64) (time.Time, time.Time, error) {
+	s, err := convertTimeField(start, minTime, section, record)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	e, err := convertTimeField(end, maxTime, section, record)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return s, e, nil
+}
 
-		CLDMapException[call] = append(CLDMapException[call], d)
+// Add an entity to the maps of db
+func (db *Database) addEntity(s *EntitiesEntity) error {
+	var err error
+	var d CLDEntity
+	var da CLDEntityByAdif
+
+	adif := s.Adif
+	prefix := s.Prefix
+
+	d.Adif = adif
+	d.Name = s.Name
+	d.Deleted = s.Deleted
+	d.Cqz = s.Cqz
+	d.Cont = s.Cont
+	d.Long = s.Long
+	d.Lat = s.Lat
+	d.Start, d.End, err = convertTimeRange(s.Start, s.End, SectionEntities, uint64(adif))
+	if err != nil {
+		return err
+	}
+	d.Whitelist = s.Whitelist
+	d.WhitelistStart, d.WhitelistEnd, err = convertTimeRange(s.WhitelistStart, s.WhitelistEnd, SectionEntities, uint64(adif))
+	if err != nil {
+		return err
 	}
 
+	db.MapEntity[prefix] = append(db.MapEntity[prefix], d)
+
+	da.Name = d.Name
+	da.Prefix = prefix
+	da.Deleted = d.Deleted
+	da.Cqz = d.Cqz
+	da.Cont = d.Cont
+	da.Long = d.Long
+	da.Lat = d.Lat
+	da.Start = d.Start
+	da.End = d.End
+	da.Whitelist = d.Whitelist
+	da.WhitelistStart = d.WhitelistStart
+	da.WhitelistEnd = d.WhitelistEnd
+
+	// Here simple assignment, NOT appending
+	db.MapEntityByAdif[adif] = da
+
+	return nil
+}
+
+// Add an exception to the maps of db
+func (db *Database) addException(s *ExceptionsException) error {
+	var err error
+	var d CLDException
+
+	d.Record = s.Record
+	call := s.Call
+	d.Entity = s.Entity
+	d.Adif = s.Adif
+	d.Cqz = s.Cqz
+	d.Cont = s.Cont
+	d.Long = s.Long
+	d.Lat = s.Lat
+	d.Start, d.End, err = convertTimeRange(s.Start, s.End, SectionExceptions, s.Record)
+	if err != nil {
+		return err
+	}
+
+	db.MapException[call] = append(db.MapException[call], d)
+
+	return nil
+}
+
+// Add a prefix to the maps of db
+func (db *Database) addPrefix(s *PrefixesPrefix) error {
+	var err error
+	var d CLDPrefix
+
 	// Former SPECIAL RULE: E5/N CLDPrefix issue solved by
 	// cty.xml version 2023-12-07T20:31:25+00:00
 
-	for _, s := range ctyXmlPrefixes {
-		var d CLDPrefix
-
-		d.Record = s.Record
-		call := s.Call
-		d.Entity = s.Entity
-		d.Adif = s.Adif
-		d.Cqz = s.Cqz
-		d.Cont = s.Cont
-		d.Long = s.Long
-		d.Lat = s.Lat
-		if len(s.Start) > 0 {
-			d.Start = ConvertTimeString(s.Start)
-		} else {
-			d.Start = minTime
-		}
-		if len(s.End) > 0 {
-			d.End = ConvertTimeString(s.End)
-		} else {
-			d.End = maxTime
-		}
-
-		CLDMapPrefix[call] = append(CLDMapPrefix[call], d)
+	d.Record = s.Record
+	call := s.Call
+	d.Entity = s.Entity
+	d.Adif = s.Adif
+	d.Cqz = s.Cqz
+	d.Cont = s.Cont
+	d.Long = s.Long
+	d.Lat = s.Lat
+	d.Start, d.End, err = convertTimeRange(s.Start, s.End, SectionPrefixes, s.Record)
+	if err != nil {
+		return err
 	}
 
-	for _, s := range ctyXmlInvalids {
-		var d CLDInvalid
+	db.MapPrefix[call] = append(db.MapPrefix[call], d)
 
-		d.Record = s.Record
-		call := s.Call
-		if len(s.Start) > 0 {
-			d.Start = ConvertTimeString(s.Start)
-		} else {
-			d.Start = minTime
-		}
-		if len(s.End) > 0 {
-			d.End = ConvertTimeString(s.End)
-		} else {
-			d.End = maxTime
-		}
+	return nil
+}
 
-		CLDMapInvalid[call] = append(CLDMapInvalid[call], d)
+// Add an invalid operation to the maps of db
+func (db *Database) addInvalid(s *InvalidOperationsInvalid) error {
+	var err error
+	var d CLDInvalid
+
+	d.Record = s.Record
+	call := s.Call
+	d.Start, d.End, err = convertTimeRange(s.Start, s.End, SectionInvalidOperations, s.Record)
+	if err != nil {
+		return err
 	}
 
-	for _, s := range ctyXmlZoneExceptions {
-		var d CLDZoneException
+	db.MapInvalid[call] = append(db.MapInvalid[call], d)
 
-		d.Record = s.Record
-		call := s.Call
-		d.Zone = s.Zone
-		if len(s.Start) > 0 {
-			d.Start = ConvertTimeString(s.Start)
-		} else {
-			d.Start = minTime
-		}
-		if len(s.End) > 0 {
-			d.End = ConvertTimeString(s.End)
-		} else {
-			d.End = maxTime
-		}
+	return nil
+}
+
+// Add a zone exception to the maps of db
+func (db *Database) addZoneException(s *ZoneExceptionsZoneException) error {
+	var err error
+	var d CLDZoneException
 
-		CLDMapZoneException[call] = append(CLDMapZoneException[call], d)
+	d.Record = s.Record
+	call := s.Call
+	d.Zone = s.Zone
+	d.Start, d.End, err = convertTimeRange(s.Start, s.End, SectionZoneExceptions, s.Record)
+	if err != nil {
+		return err
 	}
+
+	db.MapZoneException[call] = append(db.MapZoneException[call], d)
+
+	return nil
 }
@@ -0,0 +1,375 @@
+package gocldb
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadCtyXmlParseError(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+		// Offset is in the range of the text after this
+		after string
+	}{
+		{"mismatched tag", `<clublog date="2024-01-10T12:00:00+00:00"><entities>` +
+			`<entity><adif>1</adif></entities></clublog>`, "</adif>"},
+		{"truncated", `<clublog date="2024-01-10T12:00:00+00:00"><entities><entity><adif>1`,
+			"<adif>"},
+		{"invalid character", `<clublog date="2024-01-10T12:00:00+00:00"><prefixes>` +
+			"<prefix record=\"1\"><call>\x01</call></prefix></prefixes></clublog>", "<call>"},
+		{"empty", ``, ""},
+	}
+	for _, tt := range tests {
+		err := NewDatabase().LoadCtyXmlReader(strings.NewReader(tt.xml))
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%s: %v, want *ParseError", tt.name, err)
+			continue
+		}
+		min := int64(strings.Index(tt.xml, tt.after) + len(tt.after))
+		if perr.Offset < min || perr.Offset > int64(len(tt.xml)) {
+			t.Errorf("%s: Offset = %d, want %d to %d", tt.name, perr.Offset, min, len(tt.xml))
+		}
+		// An empty input has no XML syntax to report
+		var serr *xml.SyntaxError
+		if tt.xml != "" && !errors.As(err, &serr) {
+			t.Errorf("%s: %v, want *xml.SyntaxError", tt.name, err)
+		}
+	}
+}
+
+func TestLoadCtyXmlTimeFormatError(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		section string
+		record  uint64
+		value   TimeString
+	}{
+		{"exception", `<clublog date="2024-01-10T12:00:00+00:00"><exceptions>` +
+			`<exception record="7"><call>JA1XYZ</call><adif>339</adif>` +
+			`<start>2019-13-01T00:00:00+00:00</start></exception></exceptions></clublog>`,
+			"exceptions", 7, "2019-13-01T00:00:00+00:00"},
+		{"prefix end", `<clublog date="2024-01-10T12:00:00+00:00"><prefixes>` +
+			`<prefix record="42"><call>JA</call><adif>339</adif>` +
+			`<end>2019-01-01</end></prefix></prefixes></clublog>`,
+			"prefixes", 42, "2019-01-01"},
+		{"entity", `<clublog date="2024-01-10T12:00:00+00:00"><entities>` +
+			`<entity><adif>339</adif><name>JAPAN</name><prefix>JA</prefix>` +
+			`<start>yesterday</start></entity></entities></clublog>`,
+			"entities", 339, "yesterday"},
+		{"no date", `<clublog><entities></entities></clublog>`, "clublog", 0, ""},
+	}
+	for _, tt := range tests {
+		db := loadTestDatabase(t)
+		err := db.LoadCtyXmlReader(strings.NewReader(tt.xml))
+		var terr *TimeFormatError
+		if !errors.As(err, &terr) {
+			t.Errorf("%s: %v, want *TimeFormatError", tt.name, err)
+			continue
+		}
+		if terr.Section != tt.section || terr.Record != tt.record || terr.Value != tt.value {
+			t.Errorf("%s: %s record %d %q, want %s record %d %q", tt.name,
+				terr.Section, terr.Record, terr.Value, tt.section, tt.record, tt.value)
+		}
+		// The tables are kept if failed
+		if _, exists := db.MapPrefix["JA"]; !exists {
+			t.Errorf("%s: tables replaced", tt.name)
+		}
+	}
+}
+
+func TestLoadCtyXmlNotFound(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "cty.xml")
+	err := NewDatabase().LoadCtyXmlFile(missing)
+	if !errors.Is(err, ErrNotFound) || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("LoadCtyXmlFile(%q): %v, want ErrNotFound", missing, err)
+	}
+	err = LoadCtyXmlFile(missing)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("package LoadCtyXmlFile(%q): %v, want ErrNotFound", missing, err)
+	}
+	_, err = FindCtyXml(missing, t.TempDir())
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindCtyXml(): %v, want ErrNotFound (directories are skipped)", err)
+	}
+}
+
+func TestCtyXmlSearchPath(t *testing.T) {
+	env := filepath.Join(t.TempDir(), "env.xml")
+	xdg := t.TempDir()
+	home := t.TempDir()
+	t.Setenv(CtyXmlEnv, env)
+	t.Setenv("XDG_DATA_HOME", xdg)
+	t.Setenv("HOME", home)
+	xdgfile := filepath.Join(xdg, "dxcc", "cty.xml")
+	homefile := filepath.Join(home, ".local", "share", "dxcc", "cty.xml")
+
+	want := []string{env, xdgfile, homefile,
+		"/usr/local/share/dxcc/cty.xml", "/usr/share/dxcc/cty.xml"}
+	got := CtyXmlSearchPath()
+	if len(got) < len(want) || !slices.Equal(got[:len(want)], want) {
+		t.Fatalf("CtyXmlSearchPath() = %q, want prefix %q", got, want)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[len(got)-1] != filepath.Join(cwd, "cty.xml") {
+		t.Errorf("CtyXmlSearchPath() last = %q, want the current directory", got[len(got)-1])
+	}
+
+	// None of them exists yet
+	if found, err := FindCtyXml(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindCtyXml() = %q, %v, want ErrNotFound", found, err)
+	}
+	// Create the files from the last, so each one is the first in turn
+	for _, filename := range []string{homefile, xdgfile, env} {
+		err := os.MkdirAll(filepath.Dir(filename), 0o755)
+		if err == nil {
+			err = os.WriteFile(filename, []byte("<clublog/>"), 0o644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found, err := FindCtyXml(); found != filename || err != nil {
+			t.Errorf("FindCtyXml() = %q, %v, want %q", found, err, filename)
+		}
+	}
+
+	// Unset variables are skipped
+	t.Setenv(CtyXmlEnv, "")
+	t.Setenv("XDG_DATA_HOME", "")
+	want = []string{homefile, "/usr/local/share/dxcc/cty.xml"}
+	if got := CtyXmlSearchPath(); !slices.Equal(got[:len(want)], want) {
+		t.Errorf("CtyXmlSearchPath() unset = %q, want prefix %q", got, want)
+	}
+	if found, err := FindCtyXml(); found != homefile || err != nil {
+		t.Errorf("FindCtyXml() unset = %q, %v, want %q", found, err, homefile)
+	}
+}
+
+// Returns a generated cty.xml of about the size of the Club Log one
+// with the given numbers of the records
+func generateCtyXml(entities, exceptions, prefixes, invalids, zones int) []byte {
+	var b bytes.Buffer
+	const date = "2024-01-10T12:00:00+00:00"
+	fmt.Fprintf(&b, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&b, "<clublog date=%q xmlns=\"https://clublog.org/cty/v1.2\">\n", date)
+	b.WriteString("<entities>\n")
+	for i := range entities {
+		fmt.Fprintf(&b, "<entity><adif>%d</adif><name>ENTITY %d</name><prefix>P%d</prefix>"+
+			"<deleted>false</deleted><cqz>%d</cqz><cont>EU</cont><long>%d.50</long><lat>%d.25</lat>"+
+			"</entity>\n", i+1, i+1, i+1, i%40+1, i%180, i%90)
+	}
+	b.WriteString("</entities>\n<exceptions>\n")
+	for i := range exceptions {
+		fmt.Fprintf(&b, "<exception record=\"%d\"><call>X%dA%c%c%c</call><entity>ENTITY %d</entity>"+
+			"<adif>%d</adif><cqz>%d</cqz><cont>EU</cont><long>10.50</long><lat>50.25</lat>"+
+			"<start>2010-01-01T00:00:00+00:00</start><end>2010-12-31T23:59:59+00:00</end></exception>\n",
+			i+1, i%10, 'A'+i/676%26, 'A'+i/26%26, 'A'+i%26, i%entities+1, i%entities+1, i%40+1)
+	}
+	b.WriteString("</exceptions>\n<prefixes>\n")
+	for i := range prefixes {
+		fmt.Fprintf(&b, "<prefix record=\"%d\"><call>Y%c%c%d</call><entity>ENTITY %d</entity>"+
+			"<adif>%d</adif><cqz>%d</cqz><cont>EU</cont><long>10.50</long><lat>50.25</lat></prefix>\n",
+			i+1, 'A'+i/26%26, 'A'+i%26, i/676, i%entities+1, i%entities+1, i%40+1)
+	}
+	b.WriteString("</prefixes>\n<invalid_operations>\n")
+	for i := range invalids {
+		fmt.Fprintf(&b, "<invalid record=\"%d\"><call>Z%dA%c%c</call>"+
+			"<start>2015-01-01T00:00:00+00:00</start><end>2015-12-31T23:59:59+00:00</end></invalid>\n",
+			i+1, i%10, 'A'+i/26%26, 'A'+i%26)
+	}
+	b.WriteString("</invalid_operations>\n<zone_exceptions>\n")
+	for i := range zones {
+		fmt.Fprintf(&b, "<zone_exception record=\"%d\"><call>W%dZ%c%c</call><zone>%d</zone>"+
+			"<start>2012-01-01T00:00:00+00:00</start></zone_exception>\n",
+			i+1, i%10, 'A'+i/26%26, 'A'+i%26, i%40+1)
+	}
+	b.WriteString("</zone_exceptions>\n</clublog>\n")
+	return b.Bytes()
+}
+
+// Generated cty.xml for the benchmarks
+var benchCtyXml = generateCtyXml(400, 20000, 4000, 2000, 1000)
+
+// Heap usage of a load
+type loadMemory struct {
+	// Peak of HeapAlloc during the load, sampled every 100us
+	// by another goroutine, so a short spike may be missed
+	// Includes the garbage not collected yet, so depends on GOGC
+	peak uint64
+	// HeapAlloc retained by the result after GC
+	retained uint64
+}
+
+// Returns the heap usage of load above the heap before load
+func measureLoad(load func() any) loadMemory {
+	var before, ms runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	sub := func(n uint64) uint64 {
+		if n < before.HeapAlloc {
+			return 0
+		}
+		return n - before.HeapAlloc
+	}
+
+	var m loadMemory
+	done := make(chan struct{})
+	sampled := make(chan uint64)
+	go func() {
+		var peak uint64
+		for {
+			runtime.ReadMemStats(&ms)
+			peak = max(peak, ms.HeapAlloc)
+			select {
+			case <-done:
+				sampled <- peak
+				return
+			case <-time.After(100 * time.Microsecond):
+			}
+		}
+	}()
+	v := load()
+	close(done)
+	m.peak = sub(<-sampled)
+
+	runtime.GC()
+	runtime.ReadMemStats(&ms)
+	runtime.KeepAlive(v)
+	m.retained = sub(ms.HeapAlloc)
+	return m
+}
+
+// Report the peak heap during a load and the heap retained after it
+func reportLoadMemory(b *testing.B, load func() any) {
+	m := measureLoad(load)
+	b.ReportMetric(float64(m.peak), "peak-heap-B")
+	b.ReportMetric(float64(m.retained), "retained-B")
+}
+
+func TestGeneratedCtyXml(t *testing.T) {
+	db := NewDatabase()
+	err := db.LoadCtyXmlReader(bytes.NewReader(benchCtyXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, got := range map[string]int{
+		"entities":        len(db.MapEntityByAdif),
+		"exceptions":      len(db.MapException),
+		"prefixes":        len(db.MapPrefix),
+		"invalids":        len(db.MapInvalid),
+		"zone exceptions": len(db.MapZoneException),
+	} {
+		if got == 0 {
+			t.Errorf("no %s loaded", name)
+		}
+	}
+}
+
+// The given paths must not fall back to the embedded copy
+// (meaningful with -tags gocldb_embed)
+func TestLoadCtyXmlNoFallback(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "cty.xml")
+	db := NewDatabase()
+	err := db.LoadCtyXml(missing)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("LoadCtyXml(%q): %v, want ErrNotFound", missing, err)
+	}
+	err = db.LoadSnapshotOrCtyXml(missing+".cldb", missing)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("LoadSnapshotOrCtyXml(): %v, want ErrNotFound", err)
+	}
+	if db.Embedded {
+		t.Error("embedded copy loaded")
+	}
+
+	err = db.LoadCtyXml(missing, testCtyXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Source != testCtyXml {
+		t.Errorf("Source = %q, want %q", db.Source, testCtyXml)
+	}
+}
+
+// Load with the streaming decoder
+func BenchmarkLoadCtyXmlReader(b *testing.B) {
+	load := func() any {
+		db := NewDatabase()
+		err := db.LoadCtyXmlReader(bytes.NewReader(benchCtyXml))
+		if err != nil {
+			b.Fatal(err)
+		}
+		return db
+	}
+	b.SetBytes(int64(len(benchCtyXml)))
+	b.ReportAllocs()
+	for range b.N {
+		load()
+	}
+	reportLoadMemory(b, load)
+}
+
+// Load as the former loader did:
+// read the whole file, unmarshal it into Clublog,
+// build the tables, and keep the raw data
+func BenchmarkUnmarshalCtyXml(b *testing.B) {
+	type loaded struct {
+		data []byte
+		raw  *Clublog
+		db   *Database
+	}
+	load := func() any {
+		data, err := io.ReadAll(bytes.NewReader(benchCtyXml))
+		if err != nil {
+			b.Fatal(err)
+		}
+		raw := new(Clublog)
+		err = xml.Unmarshal(data, raw)
+		if err != nil {
+			b.Fatal(err)
+		}
+		db := NewDatabase()
+		for i := range raw.Entities.Entity {
+			err = db.addEntity(&raw.Entities.Entity[i])
+		}
+		for i := range raw.Exceptions.Exception {
+			err = db.addException(&raw.Exceptions.Exception[i])
+		}
+		for i := range raw.Prefixes.Prefix {
+			err = db.addPrefix(&raw.Prefixes.Prefix[i])
+		}
+		for i := range raw.InvalidOperations.Invalid {
+			err = db.addInvalid(&raw.InvalidOperations.Invalid[i])
+		}
+		for i := range raw.ZoneExceptions.ZoneException {
+			err = db.addZoneException(&raw.ZoneExceptions.ZoneException[i])
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+		db.BuildIndex()
+		return &loaded{data, raw, db}
+	}
+	b.SetBytes(int64(len(benchCtyXml)))
+	b.ReportAllocs()
+	for range b.N {
+		load()
+	}
+	reportLoadMemory(b, load)
+}
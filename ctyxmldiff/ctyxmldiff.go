@@ -0,0 +1,68 @@
+// ctyxmldiff: show semantic differences between two cty.xml releases
+// usage: ctyxmldiff [-json] old.xml new.xml
+// Exit status: 0 if no difference, 1 if differences found
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/jj1bdx/gocldb"
+	"log"
+	"os"
+)
+
+func main() {
+
+	// The variable of flag.Bool is stored AFTER flag.Parse() is executed!
+	var jsonmode = flag.Bool("json", false, "output in JSON instead of text")
+
+	flag.Usage = func() {
+		execname := os.Args[0]
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"ctyxmldiff: Club Log cty.xml semantic diff tool\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [-json] old.xml new.xml\n\n", execname)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	oldDB := gocldb.NewDatabase()
+	err := oldDB.LoadCtyXmlFile(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("LoadCtyXmlFile(%s): %v", flag.Arg(0), err)
+	}
+	newDB := gocldb.NewDatabase()
+	err = newDB.LoadCtyXmlFile(flag.Arg(1))
+	if err != nil {
+		log.Fatalf("LoadCtyXmlFile(%s): %v", flag.Arg(1), err)
+	}
+
+	result := gocldb.Diff(oldDB, newDB)
+
+	if *jsonmode {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+		if err != nil {
+			log.Fatalf("json.Encode(): %v", err)
+		}
+	} else {
+		fmt.Printf("--- %s %s\n", flag.Arg(0), result.OldVersion.Format(gocldb.ClublogTimeLayout))
+		fmt.Printf("+++ %s %s\n", flag.Arg(1), result.NewVersion.Format(gocldb.ClublogTimeLayout))
+		for _, c := range result.Changes {
+			fmt.Println(c)
+		}
+	}
+
+	if len(result.Changes) > 0 {
+		os.Exit(1)
+	}
+}
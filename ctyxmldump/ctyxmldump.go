@@ -1,23 +1,48 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/jj1bdx/gocldb"
+	"log"
 )
 
 // main program for testing loading cty.xml
+// usage: ctyxmldump [-compile out.cldb] [cty.xml]
+// cty.xml may be gzip or zip compressed
 
 func main() {
 
-	gocldb.LoadCtyXml()
+	// The variable of flag.String is stored AFTER flag.Parse() is executed!
+	var compile = flag.String("compile", "", "write a binary snapshot file instead of dumping")
 
-	fmt.Println(gocldb.CLDVersionDateTime.Format(gocldb.ClublogTimeLayout))
+	flag.Parse()
+
+	db := gocldb.NewDatabase()
+	// Search the default path if no file is given
+	err := db.LoadCtyXml(flag.Args()...)
+	if err != nil {
+		log.Fatalf("LoadCtyXml(): %v", err)
+	}
+
+	// Write a snapshot file and exit if -compile is set
+	if *compile != "" {
+		err = db.WriteSnapshotFile(*compile)
+		if err != nil {
+			log.Fatalf("WriteSnapshotFile(): %v", err)
+		}
+		fmt.Printf("%s: %s compiled from %s\n", *compile,
+			db.VersionDateTime.Format(gocldb.ClublogTimeLayout), db.Source)
+		return
+	}
+
+	fmt.Println(db.VersionDateTime.Format(gocldb.ClublogTimeLayout))
 
 	var sl int
 
-	fmt.Println("=== CLDMapEntity:", len(gocldb.CLDMapEntity))
+	fmt.Println("=== CLDMapEntity:", len(db.MapEntity))
 	sl = 0
-	for k, s := range gocldb.CLDMapEntity {
+	for k, s := range db.MapEntity {
 		fmt.Printf("%s: %#v\n", k, s)
 		l := len(s)
 		if l > sl {
@@ -29,15 +54,15 @@ func main() {
 	}
 	fmt.Println("=== CLDMapEntity max slice length:", sl)
 
-	fmt.Println("=== CLDMapEntityByAdif:", len(gocldb.CLDMapEntityByAdif))
+	fmt.Println("=== CLDMapEntityByAdif:", len(db.MapEntityByAdif))
 	sl = 0
-	for k, s := range gocldb.CLDMapEntityByAdif {
+	for k, s := range db.MapEntityByAdif {
 		fmt.Printf("%d (%#x): %#v\n", k, k, s)
 	}
 
-	fmt.Println("=== CLDMapException:", len(gocldb.CLDMapException))
+	fmt.Println("=== CLDMapException:", len(db.MapException))
 	sl = 0
-	for k, s := range gocldb.CLDMapException {
+	for k, s := range db.MapException {
 		fmt.Printf("%s: %#v\n", k, s)
 		l := len(s)
 		if l > sl {
@@ -49,9 +74,9 @@ func main() {
 	}
 	fmt.Println("=== CLDMapException max slice length:", sl)
 
-	fmt.Println("=== CLDMapPrefix:", len(gocldb.CLDMapPrefix))
+	fmt.Println("=== CLDMapPrefix:", len(db.MapPrefix))
 	sl = 0
-	for k, s := range gocldb.CLDMapPrefix {
+	for k, s := range db.MapPrefix {
 		fmt.Printf("%s: %#v\n", k, s)
 		l := len(s)
 		if l > sl {
@@ -60,9 +85,9 @@ func main() {
 	}
 	fmt.Println("=== CLDMapPrefix max slice length:", sl)
 
-	fmt.Println("=== CLDMapInvalid:", len(gocldb.CLDMapInvalid))
+	fmt.Println("=== CLDMapInvalid:", len(db.MapInvalid))
 	sl = 0
-	for k, s := range gocldb.CLDMapInvalid {
+	for k, s := range db.MapInvalid {
 		fmt.Printf("%s: %#v\n", k, s)
 		l := len(s)
 		if l > sl {
@@ -71,9 +96,9 @@ func main() {
 	}
 	fmt.Println("=== CLDMapInvalid max slice length:", sl)
 
-	fmt.Println("=== CLDMapZoneException:", len(gocldb.CLDMapZoneException))
+	fmt.Println("=== CLDMapZoneException:", len(db.MapZoneException))
 	sl = 0
-	for k, s := range gocldb.CLDMapZoneException {
+	for k, s := range db.MapZoneException {
 		fmt.Printf("%s: %#v\n", k, s)
 		l := len(s)
 		if l > sl {
@@ -84,7 +109,7 @@ func main() {
 
 	fmt.Println("=== List of CLDMapPrefix")
 	sl = 0
-	for k := range gocldb.CLDMapPrefix {
+	for k := range db.MapPrefix {
 		fmt.Printf("%s\n", k)
 	}
 	fmt.Println("=== End of List of CLDMapPrefix")
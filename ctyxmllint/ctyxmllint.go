@@ -0,0 +1,69 @@
+// ctyxmllint: check cty.xml for data consistency problems
+// usage: ctyxmllint [-text] [cty.xml]
+// Output: one JSON object per line for each problem (default)
+// Exit status: 0 if no problem, 1 if problems found
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/jj1bdx/gocldb"
+	"io"
+	"log"
+	"os"
+)
+
+func main() {
+
+	// The variable of flag.Bool is stored AFTER flag.Parse() is executed!
+	var textmode = flag.Bool("text", false, "output in text instead of JSON lines")
+
+	flag.Usage = func() {
+		execname := os.Args[0]
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"ctyxmllint: Club Log cty.xml consistency checker\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [-text] [cty.xml]\n\n", execname)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	db := gocldb.NewDatabase()
+	// Search the default path if no file is given
+	err := db.LoadCtyXml(flag.Args()...)
+	if err != nil {
+		log.Fatalf("LoadCtyXml(): %v", err)
+	}
+
+	problems := db.Validate()
+
+	err = writeProblems(os.Stdout, problems, *textmode)
+	if err != nil {
+		log.Fatalf("writeProblems(): %v", err)
+	}
+
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+// Write the problems to w, one per line,
+// in JSON or in text if textmode is true
+func writeProblems(w io.Writer, problems []gocldb.Problem, textmode bool) error {
+	encoder := json.NewEncoder(w)
+	for _, p := range problems {
+		var err error
+		if textmode {
+			_, err = fmt.Fprintln(w, p)
+		} else {
+			err = encoder.Encode(p)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/jj1bdx/gocldb"
+	"strings"
+	"testing"
+)
+
+func TestWriteProblems(t *testing.T) {
+	const filename = "../testdata/broken.xml"
+	db := gocldb.NewDatabase()
+	err := db.LoadCtyXmlFile(filename)
+	if err != nil {
+		t.Fatalf("LoadCtyXmlFile(%q): %v", filename, err)
+	}
+	problems := db.Validate()
+	if len(problems) == 0 {
+		t.Fatalf("Validate() of %s: no problem", filename)
+	}
+
+	var buf bytes.Buffer
+	err = writeProblems(&buf, problems, false)
+	if err != nil {
+		t.Fatalf("writeProblems(): %v", err)
+	}
+	// One JSON object per line in the same order
+	scanner := bufio.NewScanner(&buf)
+	var n int
+	for ; scanner.Scan(); n++ {
+		if n >= len(problems) {
+			t.Fatalf("line %d: more lines than %d problems", n+1, len(problems))
+		}
+		line := scanner.Bytes()
+		var p gocldb.Problem
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(&p)
+		if err != nil {
+			t.Fatalf("line %d: %v: %s", n+1, err, line)
+		}
+		if p != problems[n] {
+			t.Errorf("line %d = %+v, want %+v", n+1, p, problems[n])
+		}
+		if bytes.Contains(line, []byte("other_record")) != (p.OtherRecord != 0) {
+			t.Errorf("line %d: other_record not omitted when zero: %s", n+1, line)
+		}
+	}
+	if n != len(problems) {
+		t.Errorf("%d lines, want %d", n, len(problems))
+	}
+
+	buf.Reset()
+	err = writeProblems(&buf, problems, true)
+	if err != nil {
+		t.Fatalf("writeProblems() text: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(problems) || lines[0] != problems[0].String() {
+		t.Errorf("text output = %q, want %d lines of Problem.String()", lines, len(problems))
+	}
+}
@@ -0,0 +1,132 @@
+// gocldb Database instance
+
+package gocldb
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Database holds the tables of one loaded cty.xml
+// Use NewDatabase() to create an instance,
+// then load cty.xml into it
+// A loaded Database is read-only and safe for concurrent lookups;
+// to reload, load into a new instance and publish it
+// with SetDefaultDatabase() or through a Watcher
+type Database struct {
+	// Entity by prefix, returning a slice
+	MapEntity map[string][]CLDEntity
+	// Entity by adif (Entity code)
+	// Each entity code maps to only one Entity
+	MapEntityByAdif map[uint16]CLDEntityByAdif
+	// Entity Exception status by callsign, returning a slice
+	MapException map[string][]CLDException
+	// Entity by longest-match prefixes, returning a slice
+	MapPrefix map[string][]CLDPrefix
+	// DXCC-invalid status by callsign, returning a slice
+	MapInvalid map[string][]CLDInvalid
+	// Zone exception by callsign, returning a slice
+	MapZoneException map[string][]CLDZoneException
+	// Club Log Database release date and time
+	VersionDateTime time.Time
+	// Path of the loaded cty.xml file
+	// (empty if loaded from an io.Reader)
+	Source string
+	// True if loaded from the embedded copy
+	Embedded bool
+	// Local overlay records checked before the tables above
+	// (nil if none; see LoadOverlayFile())
+	Overlay *Database
+	// Special rules of the callsign check
+	// (nil for the built-in rules; see LoadSpecialRulesFile())
+	SpecialRules *SpecialRules
+	// Logger of this instance set by the options
+	// (nil for a Database not created by NewDatabase())
+	logger *slog.Logger
+	// Lookup indexes built by BuildIndex()
+	prefixTrie         *prefixTrie
+	exceptionIndex     intervalTable[CLDException]
+	invalidIndex       intervalTable[CLDInvalid]
+	zoneExceptionIndex intervalTable[CLDZoneException]
+}
+
+// The default Database used by the package-level functions
+var defaultDatabase atomic.Pointer[Database]
+
+// Serializes the updates of the compatibility global variables
+var defaultDatabaseMutex sync.Mutex
+
+func init() {
+	SetDefaultDatabase(NewDatabase())
+}
+
+// Returns an empty Database with the tables allocated
+// and the options applied
+// The logger is silent as default; see SetLogger()
+func NewDatabase(opts ...Option) *Database {
+	db := &Database{
+		MapEntity:        make(map[string][]CLDEntity, 500),
+		MapEntityByAdif:  make(map[uint16]CLDEntityByAdif, 500),
+		MapException:     make(map[string][]CLDException, 50000),
+		MapPrefix:        make(map[string][]CLDPrefix, 10000),
+		MapInvalid:       make(map[string][]CLDInvalid, 10000),
+		MapZoneException: make(map[string][]CLDZoneException, 10000),
+		logger:           newDefaultLogger(),
+	}
+	for _, opt := range opts {
+		opt(db)
+	}
+	return db
+}
+
+// Returns the default Database used by the package-level functions
+func DefaultDatabase() *Database {
+	return defaultDatabase.Load()
+}
+
+// Set the default Database used by the package-level functions
+// and the compatibility global variables
+// The package-level functions see either the old or the new Database
+// atomically; the compatibility global variables are NOT safe to read
+// while another goroutine calls this function
+func SetDefaultDatabase(db *Database) {
+	defaultDatabaseMutex.Lock()
+	defer defaultDatabaseMutex.Unlock()
+	defaultDatabase.Store(db)
+	CLDMapEntity = db.MapEntity
+	CLDMapEntityByAdif = db.MapEntityByAdif
+	CLDMapException = db.MapException
+	CLDMapPrefix = db.MapPrefix
+	CLDMapInvalid = db.MapInvalid
+	CLDMapZoneException = db.MapZoneException
+	CLDVersionDateTime = db.VersionDateTime
+	CLDEmbedded = db.Embedded
+}
+
+// Build the lookup indexes from the tables of db
+// The loaders call this; call this only after
+// changing the tables directly, before any lookup
+func (db *Database) BuildIndex() {
+	db.prefixTrie = newPrefixTrie(db.MapPrefix)
+	db.exceptionIndex = newIntervalTable(db.MapException)
+	db.invalidIndex = newIntervalTable(db.MapInvalid)
+	db.zoneExceptionIndex = newIntervalTable(db.MapZoneException)
+}
+
+// Replace the tables of db with those of nd
+// and build the lookup indexes
+// Source is cleared
+func (db *Database) replaceTables(nd *Database) {
+	db.MapEntity = nd.MapEntity
+	db.MapEntityByAdif = nd.MapEntityByAdif
+	db.MapException = nd.MapException
+	db.MapPrefix = nd.MapPrefix
+	db.MapInvalid = nd.MapInvalid
+	db.MapZoneException = nd.MapZoneException
+	db.VersionDateTime = nd.VersionDateTime
+	db.Source = ""
+	db.Embedded = false
+	db.BuildIndex()
+}
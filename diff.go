@@ -0,0 +1,292 @@
+// gocldb semantic diff between two databases
+
+package gocldb
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of Change
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// A changed field of a modified record
+type FieldChange struct {
+	// Field name, e.g., "Adif"
+	Name string `json:"name"`
+	// Old and new values formatted as strings
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// A changed record between two databases
+type Change struct {
+	// ChangeAdded, ChangeRemoved, or ChangeModified
+	Kind string `json:"kind"`
+	// Section name, e.g., SectionExceptions
+	Section string `json:"section"`
+	// Record number (ADIF entity code for entities)
+	Record uint64 `json:"record"`
+	// Map key: callsign or prefix (of the new record if modified)
+	Key string `json:"key"`
+	// Changed fields if modified
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%s %s record %d (%s)", c.Kind, c.Section, c.Record, c.Key)
+	if len(c.Fields) == 0 {
+		return s
+	}
+	fields := make([]string, 0, len(c.Fields))
+	for _, f := range c.Fields {
+		fields = append(fields, fmt.Sprintf("%s: %s -> %s", f.Name, f.Old, f.New))
+	}
+	return s + ": " + strings.Join(fields, ", ")
+}
+
+// Result of Diff()
+type DiffResult struct {
+	// Release date and time of the old and new databases
+	OldVersion time.Time `json:"old_version"`
+	NewVersion time.Time `json:"new_version"`
+	// Changes sorted by section, record, and key
+	Changes []Change `json:"changes"`
+}
+
+// Record to compare in Diff()
+type diffItem struct {
+	record uint64
+	key    string
+	start  time.Time
+	end    time.Time
+	// Struct value of the record
+	value any
+}
+
+// Key for matching records without the record number
+func (d *diffItem) fallbackKey() string {
+	return d.key + "|" + d.start.Format(ClublogTimeLayout) + "|" + d.end.Format(ClublogTimeLayout)
+}
+
+// Compare two databases and return the changed records
+// Records are matched by the record attribute,
+// or by the callsign and the time range if the record number
+// is zero or not unique; entities are matched by the ADIF entity code
+func Diff(oldDB *Database, newDB *Database) *DiffResult {
+	result := &DiffResult{
+		OldVersion: oldDB.VersionDateTime,
+		NewVersion: newDB.VersionDateTime,
+	}
+	sections := []struct {
+		name   string
+		items  func(db *Database) []diffItem
+		useKey bool
+	}{
+		{SectionEntities, diffEntities, false},
+		{SectionExceptions, func(db *Database) []diffItem {
+			return diffTable(db.MapException, func(s *CLDException) (uint64, time.Time, time.Time) {
+				return s.Record, s.Start, s.End
+			})
+		}, true},
+		{SectionPrefixes, func(db *Database) []diffItem {
+			return diffTable(db.MapPrefix, func(s *CLDPrefix) (uint64, time.Time, time.Time) {
+				return s.Record, s.Start, s.End
+			})
+		}, true},
+		{SectionInvalidOperations, func(db *Database) []diffItem {
+			return diffTable(db.MapInvalid, func(s *CLDInvalid) (uint64, time.Time, time.Time) {
+				return s.Record, s.Start, s.End
+			})
+		}, true},
+		{SectionZoneExceptions, func(db *Database) []diffItem {
+			return diffTable(db.MapZoneException, func(s *CLDZoneException) (uint64, time.Time, time.Time) {
+				return s.Record, s.Start, s.End
+			})
+		}, true},
+	}
+	for _, sec := range sections {
+		result.Changes = append(result.Changes,
+			diffSection(sec.name, sec.items(oldDB), sec.items(newDB), sec.useKey)...)
+	}
+	return result
+}
+
+// Flatten entities for Diff()
+func diffEntities(db *Database) []diffItem {
+	items := make([]diffItem, 0, len(db.MapEntityByAdif))
+	for adif, e := range db.MapEntityByAdif {
+		items = append(items, diffItem{
+			record: uint64(adif),
+			key:    e.Prefix,
+			start:  e.Start,
+			end:    e.End,
+			value:  e,
+		})
+	}
+	return items
+}
+
+// Flatten a map of slices for Diff()
+func diffTable[T any](m map[string][]T, fields func(*T) (uint64, time.Time, time.Time)) []diffItem {
+	items := make([]diffItem, 0, len(m))
+	for key, s := range m {
+		for i := range s {
+			record, start, end := fields(&s[i])
+			items = append(items, diffItem{
+				record: record,
+				key:    key,
+				start:  start,
+				end:    end,
+				value:  s[i],
+			})
+		}
+	}
+	return items
+}
+
+// Compare the records of a section
+// If useKey is true, the map key is compared as the field "Call"
+func diffSection(section string, olds []diffItem, news []diffItem, useKey bool) []Change {
+	var changes []Change
+
+	// Index of unique non-zero record numbers
+	uniqueRecords := func(items []diffItem) map[uint64]int {
+		count := make(map[uint64]int, len(items))
+		for _, d := range items {
+			count[d.record]++
+		}
+		index := make(map[uint64]int, len(items))
+		for i, d := range items {
+			if d.record != 0 && count[d.record] == 1 {
+				index[d.record] = i
+			}
+		}
+		return index
+	}
+	oldIndex := uniqueRecords(olds)
+	newIndex := uniqueRecords(news)
+
+	oldMatched := make([]bool, len(olds))
+	newMatched := make([]bool, len(news))
+	compare := func(o, n *diffItem) {
+		fields := diffFields(o.value, n.value)
+		if useKey && o.key != n.key {
+			fields = append([]FieldChange{{Name: "Call", Old: o.key, New: n.key}}, fields...)
+		}
+		if len(fields) > 0 {
+			changes = append(changes, Change{
+				Kind:    ChangeModified,
+				Section: section,
+				Record:  n.record,
+				Key:     n.key,
+				Fields:  fields,
+			})
+		}
+	}
+
+	// Match by the record number
+	for record, i := range oldIndex {
+		j, exists := newIndex[record]
+		if !exists {
+			continue
+		}
+		oldMatched[i] = true
+		newMatched[j] = true
+		compare(&olds[i], &news[j])
+	}
+
+	// Match the rest by the callsign and the time range
+	fallback := make(map[string][]int)
+	for j := range news {
+		if !newMatched[j] {
+			k := news[j].fallbackKey()
+			fallback[k] = append(fallback[k], j)
+		}
+	}
+	for i := range olds {
+		if oldMatched[i] {
+			continue
+		}
+		k := olds[i].fallbackKey()
+		candidates := fallback[k]
+		if len(candidates) == 0 {
+			continue
+		}
+		j := candidates[0]
+		fallback[k] = candidates[1:]
+		oldMatched[i] = true
+		newMatched[j] = true
+		compare(&olds[i], &news[j])
+	}
+
+	for i := range olds {
+		if !oldMatched[i] {
+			changes = append(changes, Change{
+				Kind:    ChangeRemoved,
+				Section: section,
+				Record:  olds[i].record,
+				Key:     olds[i].key,
+			})
+		}
+	}
+	for j := range news {
+		if !newMatched[j] {
+			changes = append(changes, Change{
+				Kind:    ChangeAdded,
+				Section: section,
+				Record:  news[j].record,
+				Key:     news[j].key,
+			})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return cmp.Or(
+			cmp.Compare(a.Record, b.Record),
+			cmp.Compare(a.Key, b.Key),
+			cmp.Compare(a.Kind, b.Kind),
+		)
+	})
+	return changes
+}
+
+// Compare the fields of two struct values of the same type
+func diffFields(o any, n any) []FieldChange {
+	var fields []FieldChange
+	ov := reflect.ValueOf(o)
+	nv := reflect.ValueOf(n)
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		olds := formatDiffValue(ov.Field(i))
+		news := formatDiffValue(nv.Field(i))
+		if olds != news {
+			fields = append(fields, FieldChange{Name: t.Field(i).Name, Old: olds, New: news})
+		}
+	}
+	return fields
+}
+
+// Format a field value for FieldChange
+func formatDiffValue(v reflect.Value) string {
+	switch x := v.Interface().(type) {
+	case time.Time:
+		return x.Format(ClublogTimeLayout)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	default:
+		return fmt.Sprint(x)
+	}
+}
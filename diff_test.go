@@ -0,0 +1,82 @@
+package gocldb
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	data, err := os.ReadFile(testCtyXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldxml := string(data)
+	oldDB := NewDatabase()
+	err = oldDB.LoadCtyXmlReader(strings.NewReader(oldxml))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const bs7h = `<call>BS7H</call><entity>SCARBOROUGH REEF</entity><adif>506</adif><cqz>27</cqz><cont>AS</cont><long>117.80</long><lat>15.10</lat><start>2020-01-01T00:00:00+00:00</start><end>2020-12-31T23:59:59+00:00</end></exception>`
+	tests := []struct {
+		name string
+		// Replaced in the old cty.xml for the new one
+		old, new string
+		want     []Change
+	}{
+		{"none", "", "", nil},
+		{"added", "</exceptions>",
+			`<exception record="5"><call>JA1NEW</call><entity>JAPAN</entity><adif>339</adif><cqz>25</cqz><cont>AS</cont></exception>` + "\n</exceptions>",
+			[]Change{{Kind: ChangeAdded, Section: SectionExceptions, Record: 5, Key: "JA1NEW"}}},
+		{"removed",
+			`<invalid record="200"><call>JA1INV</call><start>2022-01-01T00:00:00+00:00</start><end>2022-12-31T23:59:59+00:00</end></invalid>`, "",
+			[]Change{{Kind: ChangeRemoved, Section: SectionInvalidOperations, Record: 200, Key: "JA1INV"}}},
+		{"changed field", `<call>W1AW</call><zone>4</zone>`, `<call>W1AW</call><zone>5</zone>`,
+			[]Change{{Kind: ChangeModified, Section: SectionZoneExceptions, Record: 300, Key: "W1AW",
+				Fields: []FieldChange{{Name: "Zone", Old: "4", New: "5"}}}}},
+		{"changed fields and call", `<prefix record="100"><call>JA</call><entity>JAPAN</entity><adif>339</adif><cqz>25</cqz>`,
+			`<prefix record="100"><call>JE</call><entity>JAPAN</entity><adif>339</adif><cqz>26</cqz>`,
+			[]Change{{Kind: ChangeModified, Section: SectionPrefixes, Record: 100, Key: "JE",
+				Fields: []FieldChange{{Name: "Call", Old: "JA", New: "JE"}, {Name: "Cqz", Old: "25", New: "26"}}}}},
+		{"changed entity", `<name>JAPAN</name>`, `<name>NIPPON</name>`,
+			[]Change{{Kind: ChangeModified, Section: SectionEntities, Record: 339, Key: "JA",
+				Fields: []FieldChange{{Name: "Name", Old: "JAPAN", New: "NIPPON"}}}}},
+		// Matched by the callsign and the time range
+		{"renumbered", `<exception record="3">` + bs7h, `<exception record="9">` + bs7h,
+			[]Change{{Kind: ChangeModified, Section: SectionExceptions, Record: 9, Key: "BS7H",
+				Fields: []FieldChange{{Name: "Record", Old: "3", New: "9"}}}}},
+		{"renumbered to zero", `<exception record="3">` + bs7h, `<exception>` + bs7h,
+			[]Change{{Kind: ChangeModified, Section: SectionExceptions, Record: 0, Key: "BS7H",
+				Fields: []FieldChange{{Name: "Record", Old: "3", New: "0"}}}}},
+		{"renumbered to duplicate", `<exception record="3">` + bs7h, `<exception record="1">` + bs7h,
+			[]Change{{Kind: ChangeModified, Section: SectionExceptions, Record: 1, Key: "BS7H",
+				Fields: []FieldChange{{Name: "Record", Old: "3", New: "1"}}}}},
+		// Not matched if the time range is also changed
+		{"renumbered and moved", `<exception record="3">` + bs7h,
+			`<exception record="9">` + strings.Replace(bs7h, "2020-12-31", "2021-12-31", 1),
+			[]Change{
+				{Kind: ChangeRemoved, Section: SectionExceptions, Record: 3, Key: "BS7H"},
+				{Kind: ChangeAdded, Section: SectionExceptions, Record: 9, Key: "BS7H"},
+			}},
+	}
+	for _, tt := range tests {
+		newxml := strings.Replace(oldxml, tt.old, tt.new, 1)
+		if tt.old != "" && newxml == oldxml {
+			t.Fatalf("%s: %q not in %s", tt.name, tt.old, testCtyXml)
+		}
+		newDB := NewDatabase()
+		err := newDB.LoadCtyXmlReader(strings.NewReader(newxml))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		result := Diff(oldDB, newDB)
+		if !reflect.DeepEqual(result.Changes, tt.want) {
+			t.Errorf("%s: Diff() = %v, want %v", tt.name, result.Changes, tt.want)
+		}
+		if !result.OldVersion.Equal(oldDB.VersionDateTime) || !result.NewVersion.Equal(newDB.VersionDateTime) {
+			t.Errorf("%s: Diff() versions = %v, %v", tt.name, result.OldVersion, result.NewVersion)
+		}
+	}
+}
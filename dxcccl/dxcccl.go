@@ -1,5 +1,6 @@
 // dxcccl: search callsigns with godxcl library
 // usage: dxcccl <callsign> [time]
+//        dxcccl update [-k apikey] [-o cty.xml]
 
 package main
 
@@ -8,16 +9,28 @@ import (
 	"fmt"
 	"github.com/jj1bdx/gocldb"
 	"log"
+	"log/slog"
 	"os"
 	"strings"
 	"time"
 )
 
+// Maximum age of the embedded copy without warning
+const embeddedMaxAge = 30 * 24 * time.Hour
+
 func main() {
 
 	var err error
 	// The variable of flag.Bool is stored AFTER flag.Parse() is executed!
 	var debugmode = flag.Bool("d", false, "output debug log if set")
+	var verbose = flag.Bool("v", false, "output cty.xml path and version if set")
+	var snapshot = flag.String("s", "", "binary snapshot file path compiled by ctyxmldump -compile (cty.xml is used if stale)")
+	var overlayfile = flag.String("overlay", "", "overlay file path of local records in cty.xml format, checked before Club Log records")
+	var rulesfile = flag.String("rules", "", "special rules file path in JSON, added to or replacing the built-in rules")
+	var explain = flag.Bool("explain", false, "output the rule steps taken by the lookup if set")
+	var entities = flag.Bool("entities", false, "list the DXCC entities if set")
+	var historydir = flag.String("history", "", "directory of cty.xml releases to show the result under each release")
+	var ctyxmlfile = flag.String("f", "", "cty.xml file path, optionally gzip or zip compressed (search default path if empty)")
 
 	flag.Usage = func() {
 		execname := os.Args[0]
@@ -26,7 +39,9 @@ func main() {
 				"(c) 2023 Kenji Rikitake, JJ1BDX.\n"+
 				"\n")
 		fmt.Fprintf(flag.CommandLine.Output(),
-			"Usage: %s [-d] callsign [time] \n\n", execname)
+			"Usage: %s [-d] [-v] [-explain] [-f cty.xml] [-s snapshot] [-overlay file] [-history dir] callsign [time] \n"+
+				"       %s [-f cty.xml] [-s snapshot] -entities\n"+
+				"       %s update [-k apikey] [-o cty.xml] [-u url]\n\n", execname, execname, execname)
 		fmt.Fprintf(flag.CommandLine.Output(),
 			"Acceptable time formats:\n"+
 				"    2006-01-02T15:04:05Z (assuming UTC)\n"+
@@ -37,11 +52,60 @@ func main() {
 
 	flag.Parse()
 
-	gocldb.LoadCtyXml()
+	// Subcommand: update
+	if flag.Arg(0) == "update" {
+		runUpdate(flag.Args()[1:], *ctyxmlfile)
+		return
+	}
 
 	// Enable debug logging if -d flag is set
+	// before loading, so the loader messages are also written
 	if *debugmode {
-		gocldb.DebugLogger.SetOutput(os.Stderr)
+		gocldb.SetLogger(slog.New(slog.NewTextHandler(os.Stderr,
+			&slog.HandlerOptions{Level: slog.LevelDebug})))
+	}
+
+	var ctyxmlfiles []string
+	if *ctyxmlfile != "" {
+		ctyxmlfiles = append(ctyxmlfiles, *ctyxmlfile)
+	}
+	if *historydir != "" {
+		// Releases are loaded later
+	} else if *snapshot != "" {
+		err = gocldb.LoadSnapshotOrCtyXml(*snapshot, ctyxmlfiles...)
+		if err != nil {
+			log.Fatalf("LoadSnapshotOrCtyXml(): %v\n", err)
+		}
+	} else {
+		gocldb.LoadCtyXml(ctyxmlfiles...)
+	}
+	// The overlay and the rules are applied to each release
+	// in runHistory() if -history is given
+	if *overlayfile != "" && *historydir == "" {
+		err = gocldb.LoadOverlayFile(*overlayfile)
+		if err != nil {
+			log.Fatalf("LoadOverlayFile(): %v\n", err)
+		}
+	}
+	if *rulesfile != "" && *historydir == "" {
+		err = gocldb.LoadSpecialRulesFile(*rulesfile)
+		if err != nil {
+			log.Fatalf("LoadSpecialRulesFile(): %v\n", err)
+		}
+	}
+
+	// Warn if running on an old embedded copy
+	if gocldb.CLDEmbedded {
+		err = gocldb.CheckStaleness(embeddedMaxAge)
+		if err != nil {
+			log.Printf("Warning: %v\n", err)
+		}
+	}
+
+	// List the entities and exit
+	if *entities {
+		runEntities()
+		return
 	}
 
 	args := flag.Args()
@@ -74,8 +138,20 @@ func main() {
 		qsotime = time.Now().UTC()
 	}
 
+	// Show the results under each release and exit
+	if *historydir != "" {
+		runHistory(*historydir, call, qsotime, *overlayfile, *rulesfile)
+		return
+	}
+
 	// Look up the database
-	result, err := gocldb.CheckCallsign(call, qsotime)
+	var result gocldb.CLDCheckResult
+	var steps []gocldb.ExplainStep
+	if *explain {
+		result, steps, err = gocldb.CheckCallsignExplain(call, qsotime)
+	} else {
+		result, err = gocldb.CheckCallsign(call, qsotime)
+	}
 	if err != nil {
 		log.Printf("CheckCallsign() error: %v", err)
 	}
@@ -83,6 +159,18 @@ func main() {
 		fmt.Printf("\n")
 	}
 
+	if *verbose {
+		db := gocldb.DefaultDatabase()
+		fmt.Printf("cty.xml:     %s\n", db.Source)
+		fmt.Printf("Version:     %s\n", db.VersionDateTime.Format(gocldb.ClublogTimeLayout))
+		if db.Overlay != nil {
+			fmt.Printf("Overlay:     %s\n", db.Overlay.Source)
+		}
+		if db.SpecialRules != nil {
+			fmt.Printf("Rules:       %s\n", db.SpecialRules.Source)
+		}
+	}
+
 	fmt.Printf("Callsign:    %s\n", call)
 	fmt.Printf("QSO Time:    %s\n", qsotime.Format(time.RFC3339))
 	fmt.Printf("Entity Code: %d\n", result.Adif)
@@ -94,7 +182,47 @@ func main() {
 	fmt.Printf("Latitude:    %.2f\n", result.Lat)
 	fmt.Printf("Deleted:     %t\n", result.Deleted)
 	fmt.Printf("Blocked:     %t (by Whitelist)\n", result.BlockedByWhitelist)
+	fmt.Printf("Status:      %s\n", result.Status)
+	if *overlayfile != "" {
+		fmt.Printf("Overlaid:    %t\n", result.Overlay)
+	}
+	if *verbose {
+		printProvenance(result)
+	}
+	if *explain {
+		fmt.Printf("\nTrace:\n")
+		for i, step := range steps {
+			fmt.Printf("%3d. %s\n", i+1, step)
+		}
+	}
 
 	fmt.Printf("\n")
 	return
 }
+
+// Print the matched Club Log records
+func printProvenance(result gocldb.CLDCheckResult) {
+	records := []struct {
+		name   string
+		record uint64
+	}{
+		{"exception", result.ExceptionRecord},
+		{"prefix", result.PrefixRecord},
+		{"invalid", result.InvalidRecord},
+		{"zone_exception", result.ZoneExceptionRecord},
+	}
+	var matched []string
+	for _, r := range records {
+		if r.record != 0 {
+			matched = append(matched, fmt.Sprintf("%s %d", r.name, r.record))
+		}
+	}
+	if len(matched) > 0 {
+		fmt.Printf("Records:     %s\n", strings.Join(matched, ", "))
+	}
+	if result.MatchedKey != "" {
+		fmt.Printf("Matched Key: %s (%s - %s)\n", result.MatchedKey,
+			result.MatchedStart.Format(gocldb.ClublogTimeLayout),
+			result.MatchedEnd.Format(gocldb.ClublogTimeLayout))
+	}
+}
@@ -0,0 +1,36 @@
+// dxcccl -entities: list the DXCC entities
+
+package main
+
+import (
+	"fmt"
+	"github.com/jj1bdx/gocldb"
+	"time"
+)
+
+func runEntities() {
+	fmt.Printf("%4s %-8s %-40s %-4s %3s %8s %7s %-10s %-10s %s\n",
+		"Adif", "Prefix", "Name", "Cont", "Cqz", "Long", "Lat", "Start", "End", "Flags")
+	for _, e := range gocldb.Entities() {
+		flags := ""
+		if e.Deleted {
+			flags += "deleted "
+		}
+		if e.Whitelist {
+			flags += fmt.Sprintf("whitelist %s/%s",
+				formatEntityDate(e.WhitelistStart), formatEntityDate(e.WhitelistEnd))
+		}
+		fmt.Printf("%4d %-8s %-40s %-4s %3d %8.2f %7.2f %-10s %-10s %s\n",
+			e.Adif, e.Prefix, e.Name, e.Cont, e.Cqz, e.Long, e.Lat,
+			formatEntityDate(e.Start), formatEntityDate(e.End), flags)
+	}
+}
+
+// Format the date of a validity time range,
+// or "-" if unbounded
+func formatEntityDate(t time.Time) string {
+	if t.Year() <= 1 || t.Year() >= 9999 {
+		return "-"
+	}
+	return t.Format(time.DateOnly)
+}
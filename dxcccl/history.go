@@ -0,0 +1,71 @@
+// dxcccl -history: show the result under each cty.xml release
+
+package main
+
+import (
+	"fmt"
+	"github.com/jj1bdx/gocldb"
+	"log"
+	"time"
+)
+
+func runHistory(dir string, call string, qsotime time.Time, overlayfile string, rulesfile string) {
+	h, err := gocldb.LoadHistory(dir)
+	if err != nil {
+		log.Fatalf("LoadHistory(): %v\n", err)
+	}
+	// Apply the same overlay and rules to each release
+	var overlay *gocldb.Database
+	if overlayfile != "" {
+		overlay = gocldb.NewDatabase()
+		err = overlay.LoadCtyXmlFile(overlayfile)
+		if err != nil {
+			log.Fatalf("LoadCtyXmlFile(): %v\n", err)
+		}
+	}
+	var rules *gocldb.SpecialRules
+	if rulesfile != "" {
+		rules, err = gocldb.ReadSpecialRulesFile(rulesfile)
+		if err != nil {
+			log.Fatalf("ReadSpecialRulesFile(): %v\n", err)
+		}
+	}
+	for i, db := range h.Releases {
+		h.Releases[i] = db.WithOverlay(overlay).WithSpecialRules(rules)
+	}
+	br := h.Blame(call, qsotime)
+
+	fmt.Printf("Callsign:    %s\n", call)
+	fmt.Printf("QSO Time:    %s\n", qsotime.Format(time.RFC3339))
+	fmt.Printf("Releases:    %d\n", len(br.Entries))
+	if overlay != nil {
+		fmt.Printf("Overlay:     %s\n", overlay.Source)
+	}
+	if rules != nil {
+		fmt.Printf("Rules:       %s\n", rules.Source)
+	}
+	if br.FirstChange >= 0 {
+		fmt.Printf("First changed by: %s\n",
+			br.Entries[br.FirstChange].Version.Format(gocldb.ClublogTimeLayout))
+		fmt.Printf("Last changed by:  %s\n",
+			br.Entries[br.LastChange].Version.Format(gocldb.ClublogTimeLayout))
+	} else {
+		fmt.Printf("Not changed\n")
+	}
+	fmt.Printf("\n")
+
+	for _, e := range br.Entries {
+		mark := " "
+		if e.Changed {
+			mark = "*"
+		}
+		result := e.Result
+		fmt.Printf("%s %s %4d %-30s %-8s CQ%02d %s",
+			mark, e.Version.Format(gocldb.ClublogTimeLayout),
+			result.Adif, result.Name, result.Prefix, result.Cqz, e.Source)
+		if e.Err != nil {
+			fmt.Printf(" (error: %v)", e.Err)
+		}
+		fmt.Printf("\n")
+	}
+}
@@ -0,0 +1,66 @@
+// dxcccl update: download cty.xml from Club Log
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/jj1bdx/gocldb"
+	"log"
+	"os"
+	"time"
+)
+
+// Environment variable name for the Club Log API key
+const apiKeyEnv = "CLUBLOG_API_KEY"
+
+func runUpdate(args []string, ctyxmlfile string) {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	var apikey = fs.String("k", os.Getenv(apiKeyEnv),
+		"Club Log API key (default: $"+apiKeyEnv+")")
+	var destpath = fs.String("o", ctyxmlfile,
+		"cty.xml file path to update (default: -f or the found cty.xml)")
+	var baseurl = fs.String("u", gocldb.UpdateURL, "cty.xml endpoint URL")
+	var timeout = fs.Duration("t", 60*time.Second, "download timeout")
+	fs.Parse(args)
+
+	if *apikey == "" {
+		log.Fatalf("update: API key required (-k or $%s)\n", apiKeyEnv)
+	}
+	dest := *destpath
+	if dest == "" {
+		found, err := gocldb.FindCtyXml()
+		if err != nil {
+			log.Fatalf("update: -o required: %v\n", err)
+		}
+		dest = found
+	}
+
+	// Use the release date of the current file if loadable
+	var current time.Time
+	db := gocldb.NewDatabase()
+	err := db.LoadCtyXmlFile(dest)
+	if err == nil {
+		current = db.VersionDateTime
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	u := &gocldb.Updater{BaseURL: *baseurl, Current: current}
+	newdb, err := u.Update(ctx, *apikey, dest)
+	if errors.Is(err, gocldb.ErrNotNewer) {
+		fmt.Printf("Not updated: %s is up to date\n", dest)
+		return
+	}
+	if err != nil {
+		log.Fatalf("update: %v\n", err)
+	}
+	if newdb == nil {
+		fmt.Printf("Not modified: %s\n", dest)
+		return
+	}
+	fmt.Printf("Updated:     %s\n", dest)
+	fmt.Printf("Version:     %s\n", newdb.VersionDateTime.Format(gocldb.ClublogTimeLayout))
+}
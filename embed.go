@@ -0,0 +1,23 @@
+//go:build gocldb_embed
+
+// gocldb embedded fallback database
+// Enabled with the build tag gocldb_embed
+
+package gocldb
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed embedded
+var embeddedDir embed.FS
+
+// Returns the file system of the embedded files
+func embeddedFS() fs.FS {
+	sub, err := fs.Sub(embeddedDir, "embedded")
+	if err != nil {
+		return nil
+	}
+	return sub
+}
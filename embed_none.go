@@ -0,0 +1,14 @@
+//go:build !gocldb_embed
+
+// gocldb without embedded fallback database
+
+package gocldb
+
+import (
+	"io/fs"
+)
+
+// Returns nil since no file is embedded
+func embeddedFS() fs.FS {
+	return nil
+}
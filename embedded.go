@@ -0,0 +1,124 @@
+// gocldb embedded fallback database handling
+
+package gocldb
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// Embedded file names, searched in this order
+var embeddedFilenames = []string{
+	"cty.cldb",
+	"cty.xml",
+	"cty.xml.gz",
+	"cty.xml.zip",
+}
+
+// Prefix of Database.Source when loaded from the embedded copy
+const EmbeddedSourcePrefix = "embedded:"
+
+// Returns true if an embedded copy of the database exists
+// (only when built with the build tag gocldb_embed)
+func HasEmbedded() bool {
+	_, err := findEmbedded()
+	return err == nil
+}
+
+// Find the embedded file name
+// Returns ErrNotFound if no file is embedded
+func findEmbedded() (string, error) {
+	efs := embeddedFS()
+	if efs == nil {
+		return "", ErrNotFound
+	}
+	for _, name := range embeddedFilenames {
+		_, err := fs.Stat(efs, name)
+		if err == nil {
+			return name, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// Load the embedded copy of the database into db
+// db.Embedded is set to true
+// Returns ErrNotFound if no file is embedded
+func (db *Database) LoadEmbedded() error {
+	name, err := findEmbedded()
+	if err != nil {
+		return err
+	}
+	fp, err := embeddedFS().Open(name)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	if name == embeddedFilenames[0] {
+		err = db.LoadSnapshotReader(fp)
+	} else {
+		err = db.LoadCtyXmlReader(fp)
+	}
+	if err != nil {
+		return err
+	}
+	db.Source = EmbeddedSourcePrefix + name
+	db.Embedded = true
+	return nil
+}
+
+// Error returned when the database is older than the given maximum age
+type StaleError struct {
+	// Release date and time of the database
+	Version time.Time
+	// Age of the database
+	Age time.Duration
+	// True if the database is the embedded copy
+	Embedded bool
+}
+
+func (e *StaleError) Error() string {
+	name := "cty.xml"
+	if e.Embedded {
+		name = "embedded cty.xml"
+	}
+	return fmt.Sprintf("%s is %d days old (released %s)",
+		name, int(e.Age.Hours()/24), e.Version.Format(ClublogTimeLayout))
+}
+
+// Returns the age of db at now
+func (db *Database) Age(now time.Time) time.Duration {
+	return now.Sub(db.VersionDateTime)
+}
+
+// Check if db is older than maxAge at now
+// Returns *StaleError if older, or nil
+func (db *Database) CheckStaleness(now time.Time, maxAge time.Duration) error {
+	age := db.Age(now)
+	if age <= maxAge {
+		return nil
+	}
+	return &StaleError{
+		Version:  db.VersionDateTime,
+		Age:      age,
+		Embedded: db.Embedded,
+	}
+}
+
+// Check if the default Database is older than maxAge
+// See (*Database).CheckStaleness()
+func CheckStaleness(maxAge time.Duration) error {
+	return defaultDatabase.Load().CheckStaleness(time.Now(), maxAge)
+}
+
+// Load the embedded copy into db if err is ErrNotFound;
+// otherwise return err
+func (db *Database) fallbackEmbedded(err error) error {
+	if !errors.Is(err, ErrNotFound) || !HasEmbedded() {
+		return err
+	}
+	db.Logger().Debug("cty.xml not found, using the embedded copy")
+	return db.LoadEmbedded()
+}
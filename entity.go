@@ -0,0 +1,109 @@
+// gocldb DXCC entity metadata
+
+package gocldb
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// DXCC entity metadata
+// A copy of the loaded record; changing it does not affect the Database
+type EntityInfo struct {
+	// DXCC Entity Code
+	Adif uint16
+	// Entity Name
+	Name string
+	// Entity prefix
+	Prefix string
+	// Continent (ADIF Field CONT)
+	Cont string
+	// CQ Zone Number
+	Cqz uint8
+	// Longitude
+	Long float64
+	// Latitude
+	Lat float64
+	// Validity time range
+	Start time.Time
+	End   time.Time
+	// True if a deleted DXCC entity
+	Deleted bool
+	// True if whitelisted
+	Whitelist bool
+	// Whitelist time range (valid only if Whitelist is true)
+	WhitelistStart time.Time
+	WhitelistEnd   time.Time
+}
+
+// Convert a CLDEntityByAdif to EntityInfo
+func newEntityInfo(adif uint16, e CLDEntityByAdif) EntityInfo {
+	return EntityInfo{
+		Adif:           adif,
+		Name:           e.Name,
+		Prefix:         e.Prefix,
+		Cont:           e.Cont,
+		Cqz:            e.Cqz,
+		Long:           e.Long,
+		Lat:            e.Lat,
+		Start:          e.Start,
+		End:            e.End,
+		Deleted:        e.Deleted,
+		Whitelist:      e.Whitelist,
+		WhitelistStart: e.WhitelistStart,
+		WhitelistEnd:   e.WhitelistEnd,
+	}
+}
+
+// Returns the entity of the ADIF entity code in db
+// If bool is true, the entity exists; if false, not found
+func (db *Database) Entity(adif uint16) (EntityInfo, bool) {
+	e, exists := db.MapEntityByAdif[adif]
+	if !exists {
+		return EntityInfo{}, false
+	}
+	return newEntityInfo(adif, e), true
+}
+
+// Returns all entities in db sorted by the ADIF entity code
+func (db *Database) Entities() []EntityInfo {
+	adifs := slices.Sorted(maps.Keys(db.MapEntityByAdif))
+	entities := make([]EntityInfo, 0, len(adifs))
+	for _, adif := range adifs {
+		entities = append(entities, newEntityInfo(adif, db.MapEntityByAdif[adif]))
+	}
+	return entities
+}
+
+// Returns the entities in db valid at the given time
+// sorted by the ADIF entity code
+// Note well: a deleted entity is included
+// if t is in its validity time range
+func (db *Database) ActiveEntities(t time.Time) []EntityInfo {
+	var entities []EntityInfo
+	for _, e := range db.Entities() {
+		if timeInRange(t, e.Start, e.End) {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+// Returns the entity of the ADIF entity code in the default Database
+// See (*Database).Entity()
+func Entity(adif uint16) (EntityInfo, bool) {
+	return defaultDatabase.Load().Entity(adif)
+}
+
+// Returns all entities in the default Database
+// See (*Database).Entities()
+func Entities() []EntityInfo {
+	return defaultDatabase.Load().Entities()
+}
+
+// Returns the entities in the default Database valid at the given time
+// See (*Database).ActiveEntities()
+func ActiveEntities(t time.Time) []EntityInfo {
+	return defaultDatabase.Load().ActiveEntities(t)
+}
@@ -0,0 +1,91 @@
+package gocldb
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEntities(t *testing.T) {
+	data, err := os.ReadFile(testCtyXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Add a deleted entity
+	const ddr = `<entity><adif>229</adif><name>GERMAN DEMOCRATIC REPUBLIC</name><prefix>Y2</prefix><deleted>true</deleted><cqz>14</cqz><cont>EU</cont><long>13.40</long><lat>52.50</lat><end>1990-10-02T23:59:59+00:00</end></entity>`
+	db := NewDatabase()
+	err = db.LoadCtyXmlReader(strings.NewReader(
+		strings.Replace(string(data), "</entities>", ddr+"\n</entities>", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entities := db.Entities()
+	if len(entities) != len(db.MapEntityByAdif) {
+		t.Fatalf("Entities() = %d entities, want %d", len(entities), len(db.MapEntityByAdif))
+	}
+	for i, e := range entities {
+		if i > 0 && entities[i-1].Adif >= e.Adif {
+			t.Errorf("Entities()[%d] = %d after %d: not sorted", i, e.Adif, entities[i-1].Adif)
+		}
+		if !adifContinents[e.Cont] {
+			t.Errorf("Entities()[%d] = %d: Cont %q", i, e.Adif, e.Cont)
+		}
+	}
+
+	tests := []struct {
+		adif   uint16
+		name   string
+		prefix string
+		cont   string
+		cqz    uint8
+	}{
+		{339, "JAPAN", "JA", "AS", 25},
+		{177, "MINAMI TORISHIMA", "JD/M", "OC", 27},
+		{229, "GERMAN DEMOCRATIC REPUBLIC", "Y2", "EU", 14},
+	}
+	for _, tt := range tests {
+		e, exists := db.Entity(tt.adif)
+		if !exists || e.Adif != tt.adif || e.Name != tt.name || e.Prefix != tt.prefix ||
+			e.Cont != tt.cont || e.Cqz != tt.cqz {
+			t.Errorf("Entity(%d) = %+v, %t", tt.adif, e, exists)
+		}
+	}
+	if e, exists := db.Entity(999); exists {
+		t.Errorf("Entity(999) = %+v, want not found", e)
+	}
+	if e, _ := db.Entity(229); !e.Deleted || !e.End.Equal(mustTime(t, "1990-10-02T23:59:59Z")) {
+		t.Errorf("Entity(229) = %+v, want deleted", e)
+	}
+
+	// Changing the result does not affect the Database
+	entities[0].Name = "CHANGED"
+	if e, _ := db.Entity(entities[0].Adif); e.Name == "CHANGED" {
+		t.Errorf("Entities() shares the records")
+	}
+
+	// The deleted entity is active until its end
+	for _, at := range []struct {
+		time    string
+		deleted bool
+	}{
+		{"1990-10-02T23:59:59Z", true},
+		{"1990-10-03T00:00:00Z", false},
+		{"2024-01-01T00:00:00Z", false},
+	} {
+		active := db.ActiveEntities(mustTime(t, at.time))
+		var found bool
+		for i, e := range active {
+			if i > 0 && active[i-1].Adif >= e.Adif {
+				t.Errorf("ActiveEntities(%s) not sorted", at.time)
+			}
+			found = found || e.Adif == 229
+		}
+		if found != at.deleted {
+			t.Errorf("ActiveEntities(%s) includes 229: %t, want %t", at.time, found, at.deleted)
+		}
+		if want := len(entities) - 1; !at.deleted && len(active) != want {
+			t.Errorf("ActiveEntities(%s) = %d entities, want %d", at.time, len(active), want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// gocldb lookup trace of a callsign check
+
+package gocldb
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Names of the steps in ExplainStep.Step
+const (
+	StepMalformed          = "malformed"
+	StepInvalid            = "invalid"
+	StepAeronauticalMobile = "aeronautical_mobile"
+	StepMaritimeMobile     = "maritime_mobile"
+	StepException          = "exception"
+	StepSwapped            = "swapped"
+	StepDistractionSuffix  = "distraction_suffix"
+	StepCallArea           = "call_area"
+	StepReferencePrefix    = "reference_prefix"
+	StepSpecialRule        = "special_rule"
+	StepPrefix             = "prefix"
+	StepZoneException      = "zone_exception"
+	StepWhitelist          = "whitelist"
+)
+
+// A rule step taken by a callsign check
+type ExplainStep struct {
+	// Name of the step, e.g., StepException
+	Step string `json:"step"`
+	// Callsign, prefix, or suffix the step applies to
+	Call string `json:"call"`
+	// Rewritten callsign or prefix, if rewritten
+	NewCall string `json:"new_call,omitempty"`
+	// Record number of the matched record
+	// (ADIF entity code for StepWhitelist)
+	Record uint64 `json:"record,omitempty"`
+	// True if the record matched or the rule applied
+	// Always false for StepSwapped, which only rewrites the callsign;
+	// the following StepException tells if the swapped callsign matched
+	Matched bool `json:"matched"`
+	// Human-readable description
+	Detail string `json:"detail,omitempty"`
+}
+
+func (s ExplainStep) String() string {
+	str := s.Step + " " + s.Call
+	if s.NewCall != "" {
+		str += " -> " + s.NewCall
+	}
+	switch {
+	case s.Step == StepSwapped:
+		// No matching status of a rewrite only
+	case s.Matched:
+		str += ": matched"
+	default:
+		str += ": not matched"
+	}
+	if s.Record != 0 {
+		str += fmt.Sprintf(" record %d", s.Record)
+	}
+	if s.Detail != "" {
+		str += " (" + s.Detail + ")"
+	}
+	return str
+}
+
+// Returns "overlay" for the detail of a step
+// if the record is from the overlay
+func overlayDetail(overlay bool) string {
+	if overlay {
+		return "overlay"
+	}
+	return ""
+}
+
+// Parse a callsign and time with db
+// and return the result with the rule steps taken in order
+// See (*Database).CheckCallsign()
+func (db *Database) CheckCallsignExplain(call string, qsotime time.Time) (CLDCheckResult, []ExplainStep, error) {
+	lc := newLookupContext()
+	lc.explain = true
+	result, err := db.checkCallsign(context.Background(), call, qsotime, lc)
+	return result, lc.steps, err
+}
+
+// Parse a callsign and time with the default Database
+// and return the result with the rule steps taken in order
+// See (*Database).CheckCallsignExplain()
+func CheckCallsignExplain(call string, qsotime time.Time) (CLDCheckResult, []ExplainStep, error) {
+	return defaultDatabase.Load().CheckCallsignExplain(call, qsotime)
+}
@@ -0,0 +1,37 @@
+package gocldb
+
+import (
+	"testing"
+)
+
+func TestCheckCallsignExplainSwapped(t *testing.T) {
+	db := loadTestDatabase(t)
+	r, steps, err := db.CheckCallsignExplain("JJ1BDX/KL7", mustTime(t, "2023-01-15T00:00:00Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Adif != 6 || r.ExceptionRecord != 4 {
+		t.Errorf("Adif = %d, ExceptionRecord = %d, want 6 and 4", r.Adif, r.ExceptionRecord)
+	}
+	want := []string{
+		"invalid JJ1BDX/KL7: not matched",
+		"exception JJ1BDX/KL7: not matched",
+		"swapped JJ1BDX/KL7 -> KL7/JJ1BDX",
+		"exception KL7/JJ1BDX: matched record 4",
+	}
+	var got []string
+	for _, s := range steps {
+		got = append(got, s.String())
+	}
+	if len(got) < len(want) {
+		t.Fatalf("steps = %q, want %q first", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("steps[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if steps[2].Matched {
+		t.Error("swapped step is Matched")
+	}
+}
@@ -0,0 +1,96 @@
+package gocldb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test fixture of cty.xml
+const testCtyXml = "testdata/cty.xml"
+
+// Returns a new Database loaded from the test fixture
+func loadTestDatabase(tb testing.TB) *Database {
+	tb.Helper()
+	db := NewDatabase()
+	err := db.LoadCtyXmlFile(testCtyXml)
+	if err != nil {
+		tb.Fatalf("LoadCtyXmlFile(%q): %v", testCtyXml, err)
+	}
+	return db
+}
+
+// Returns the UTC time of the RFC3339 string s
+func mustTime(tb testing.TB, s string) time.Time {
+	tb.Helper()
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		tb.Fatalf("time.Parse(%q): %v", s, err)
+	}
+	return t
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Deep equality where time.Time values are compared with Equal(),
+// e.g., for the times decoded in UTC by the snapshot loader
+func equalValues(a, b reflect.Value) bool {
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Equal(b.Interface().(time.Time))
+	}
+	switch a.Kind() {
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for _, k := range a.MapKeys() {
+			bv := b.MapIndex(k)
+			if !bv.IsValid() || !equalValues(a.MapIndex(k), bv) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := range a.Len() {
+			if !equalValues(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := range a.NumField() {
+			if !equalValues(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// Check that the tables of got are the same as those of want
+func checkSameTables(t *testing.T, got, want *Database) {
+	t.Helper()
+	tables := []struct {
+		name      string
+		got, want any
+	}{
+		{"MapEntity", got.MapEntity, want.MapEntity},
+		{"MapEntityByAdif", got.MapEntityByAdif, want.MapEntityByAdif},
+		{"MapException", got.MapException, want.MapException},
+		{"MapPrefix", got.MapPrefix, want.MapPrefix},
+		{"MapInvalid", got.MapInvalid, want.MapInvalid},
+		{"MapZoneException", got.MapZoneException, want.MapZoneException},
+	}
+	for _, tt := range tables {
+		if !equalValues(reflect.ValueOf(tt.got), reflect.ValueOf(tt.want)) {
+			t.Errorf("%s differs", tt.name)
+		}
+	}
+	if !got.VersionDateTime.Equal(want.VersionDateTime) {
+		t.Errorf("VersionDateTime = %v, want %v", got.VersionDateTime, want.VersionDateTime)
+	}
+}
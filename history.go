@@ -0,0 +1,146 @@
+// gocldb multi-release history store and blame of a callsign result
+
+package gocldb
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// File name suffixes loaded by LoadHistory()
+var historySuffixes = []string{".xml", ".gz", ".zip", ".cldb"}
+
+// History holds multiple releases of cty.xml
+// sorted by the release date and time
+// Note well: each release is a full Database in memory
+type History struct {
+	// Releases sorted by VersionDateTime, oldest first
+	Releases []*Database
+}
+
+// Load all cty.xml releases in the directory dir
+// Files with the suffix .xml, .gz, .zip (cty.xml) and .cldb (snapshot)
+// are loaded; other files are ignored
+// Releases with the same date are loaded only once
+func LoadHistory(dir string) (*History, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	h := &History{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !slices.ContainsFunc(historySuffixes, func(suffix string) bool {
+			return strings.HasSuffix(name, suffix)
+		}) {
+			continue
+		}
+		filename := filepath.Join(dir, name)
+		db := NewDatabase()
+		if strings.HasSuffix(name, ".cldb") {
+			err = db.LoadSnapshotFile(filename)
+		} else {
+			err = db.LoadCtyXmlFile(filename)
+		}
+		if err != nil {
+			return nil, err
+		}
+		h.Add(db)
+	}
+	return h, nil
+}
+
+// Add a release to h keeping the order
+// A release with the same date as an existing one is ignored
+// Returns true if added
+func (h *History) Add(db *Database) bool {
+	i, found := slices.BinarySearchFunc(h.Releases, db.VersionDateTime,
+		func(r *Database, t time.Time) int {
+			return r.VersionDateTime.Compare(t)
+		})
+	if found {
+		return false
+	}
+	h.Releases = slices.Insert(h.Releases, i, db)
+	return true
+}
+
+// Result of a callsign under a release
+type BlameEntry struct {
+	// Release date and time
+	Version time.Time
+	// Path of the release file
+	Source string
+	// CheckCallsign() result under the release
+	Result CLDCheckResult
+	// CheckCallsign() error under the release
+	Err error
+	// True if the result differs from the previous release
+	Changed bool
+}
+
+// Result of Blame()
+type BlameResult struct {
+	// Callsign and QSO time
+	Call    string
+	QsoTime time.Time
+	// Results under each release, oldest first
+	Entries []BlameEntry
+	// Index of Entries where the result first changed, or -1
+	FirstChange int
+	// Index of Entries where the result last changed, or -1
+	LastChange int
+}
+
+// Check the callsign and QSO time under each release
+// and find which release changed the result
+func (h *History) Blame(call string, qsotime time.Time) *BlameResult {
+	br := &BlameResult{
+		Call:        call,
+		QsoTime:     qsotime,
+		Entries:     make([]BlameEntry, 0, len(h.Releases)),
+		FirstChange: -1,
+		LastChange:  -1,
+	}
+	for i, db := range h.Releases {
+		result, err := db.CheckCallsign(call, qsotime)
+		entry := BlameEntry{
+			Version: db.VersionDateTime,
+			Source:  db.Source,
+			Result:  result,
+			Err:     err,
+		}
+		if i > 0 {
+			prev := &br.Entries[i-1]
+			entry.Changed = !sameCheckResult(&prev.Result, &result) ||
+				prev.Err != err
+		}
+		if entry.Changed {
+			if br.FirstChange < 0 {
+				br.FirstChange = i
+			}
+			br.LastChange = i
+		}
+		br.Entries = append(br.Entries, entry)
+	}
+	return br
+}
+
+// Compare the public outcome fields of two results
+func sameCheckResult(a *CLDCheckResult, b *CLDCheckResult) bool {
+	return a.Adif == b.Adif &&
+		a.Name == b.Name &&
+		a.Prefix == b.Prefix &&
+		a.Cqz == b.Cqz &&
+		a.Cont == b.Cont &&
+		a.Long == b.Long &&
+		a.Lat == b.Lat &&
+		a.Deleted == b.Deleted &&
+		a.BlockedByWhitelist == b.BlockedByWhitelist &&
+		a.Invalid == b.Invalid &&
+		a.Overlay == b.Overlay &&
+		a.Status == b.Status
+}
@@ -0,0 +1,93 @@
+package gocldb
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHistoryBlame(t *testing.T) {
+	data, err := os.ReadFile(testCtyXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := string(data)
+	const date = `date="2024-01-10T12:00:00+00:00"`
+	const japan = `<call>JA</call><entity>JAPAN</entity><adif>339</adif><cqz>25</cqz>`
+	const w1aw = `<call>W1AW</call><zone>4</zone>`
+	release := func(day string, cqz string, zone string) string {
+		s := strings.Replace(base, date, `date="2024-`+day+`T12:00:00+00:00"`, 1)
+		s = strings.Replace(s, w1aw, strings.Replace(w1aw, "4", zone, 1), 1)
+		return strings.Replace(s, japan, strings.Replace(japan, "25", cqz, 1), 1)
+	}
+	dir := t.TempDir()
+	// File names are not in the release order
+	files := map[string]string{
+		"d.xml":     release("01-10", "25", "4"),
+		"c.xml":     release("02-10", "26", "4"),
+		"b.xml":     release("03-10", "26", "3"),
+		"a.xml":     release("04-10", "25", "3"),
+		"same.xml":  release("03-10", "27", "4"),
+		"notes.txt": "not a release",
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	h, err := LoadHistory(dir)
+	if err != nil {
+		t.Fatalf("LoadHistory(): %v", err)
+	}
+	if len(h.Releases) != 4 {
+		t.Fatalf("LoadHistory() = %d releases, want 4", len(h.Releases))
+	}
+	for i := 1; i < len(h.Releases); i++ {
+		if !h.Releases[i-1].VersionDateTime.Before(h.Releases[i].VersionDateTime) {
+			t.Errorf("Releases[%d] not sorted: %v", i, h.Releases[i].VersionDateTime)
+		}
+	}
+	// The files are read in the name order,
+	// and the first release of the same date is kept
+	for i, name := range []string{"d.xml", "c.xml", "b.xml", "a.xml"} {
+		if got := filepath.Base(h.Releases[i].Source); got != name {
+			t.Errorf("Releases[%d].Source = %q, want %q", i, got, name)
+		}
+	}
+
+	qsotime := mustTime(t, "2024-01-01T00:00:00Z")
+	tests := []struct {
+		call        string
+		first, last int
+		cqz         []uint8
+	}{
+		{"JA1ABC", 1, 3, nil},
+		{"JJ1BDX", -1, -1, []uint8{25, 25, 25, 25}},
+		// Changed only by the release of 2024-03-10
+		{"W1AW", 2, 2, []uint8{4, 4, 3, 3}},
+	}
+	for _, tt := range tests {
+		br := h.Blame(tt.call, qsotime)
+		if br.Call != tt.call || !br.QsoTime.Equal(qsotime) || len(br.Entries) != 4 {
+			t.Fatalf("Blame(%s) = %s %v with %d entries", tt.call, br.Call, br.QsoTime, len(br.Entries))
+		}
+		if br.FirstChange != tt.first || br.LastChange != tt.last {
+			t.Errorf("Blame(%s) changes = %d, %d, want %d, %d",
+				tt.call, br.FirstChange, br.LastChange, tt.first, tt.last)
+		}
+		for i, e := range br.Entries {
+			if !e.Version.Equal(h.Releases[i].VersionDateTime) || e.Source != h.Releases[i].Source {
+				t.Errorf("Blame(%s).Entries[%d] = %v %s", tt.call, i, e.Version, e.Source)
+			}
+			if e.Changed != (i == tt.first || i == tt.last) {
+				t.Errorf("Blame(%s).Entries[%d].Changed = %t", tt.call, i, e.Changed)
+			}
+			if tt.cqz != nil && e.Result.Cqz != tt.cqz[i] {
+				t.Errorf("Blame(%s).Entries[%d].Result.Cqz = %d, want %d",
+					tt.call, i, e.Result.Cqz, tt.cqz[i])
+			}
+		}
+	}
+}
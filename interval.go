@@ -0,0 +1,165 @@
+// gocldb sorted interval index for the time-ranged records
+
+package gocldb
+
+import (
+	"slices"
+	"time"
+)
+
+// Records with a validity time range
+type timeRanged interface {
+	timeRange() (time.Time, time.Time)
+}
+
+func (d CLDException) timeRange() (time.Time, time.Time)     { return d.Start, d.End }
+func (d CLDPrefix) timeRange() (time.Time, time.Time)        { return d.Start, d.End }
+func (d CLDInvalid) timeRange() (time.Time, time.Time)       { return d.Start, d.End }
+func (d CLDZoneException) timeRange() (time.Time, time.Time) { return d.Start, d.End }
+
+// Keys with fewer records than this are scanned linearly
+// without building the index
+const intervalIndexMinRecords = 8
+
+// Index of the time-ranged records of a key
+// sorted by the start time
+type intervalIndex struct {
+	// Start and end times sorted by the start time
+	starts []time.Time
+	ends   []time.Time
+	// Positions of the records in the original slice
+	positions []int
+	// True if any two time ranges overlap
+	overlapping bool
+}
+
+// Build an interval index of the records
+func newIntervalIndex[T timeRanged](records []T) *intervalIndex {
+	n := len(records)
+	idx := &intervalIndex{
+		starts:    make([]time.Time, n),
+		ends:      make([]time.Time, n),
+		positions: make([]int, n),
+	}
+	for i := range records {
+		idx.positions[i] = i
+	}
+	slices.SortStableFunc(idx.positions, func(a, b int) int {
+		sa, _ := records[a].timeRange()
+		sb, _ := records[b].timeRange()
+		return sa.Compare(sb)
+	})
+	var maxEnd time.Time
+	for i, p := range idx.positions {
+		idx.starts[i], idx.ends[i] = records[p].timeRange()
+		if i > 0 && !idx.starts[i].After(maxEnd) {
+			idx.overlapping = true
+		}
+		if i == 0 || idx.ends[i].After(maxEnd) {
+			maxEnd = idx.ends[i]
+		}
+	}
+	return idx
+}
+
+// Call f with the positions in the original slice
+// of each pair of the overlapping time ranges,
+// the record starting later first, in the order of the start times
+func (idx *intervalIndex) overlaps(f func(int, int)) {
+	if !idx.overlapping {
+		return
+	}
+	for i := 1; i < len(idx.starts); i++ {
+		for j := 0; j < i; j++ {
+			if !idx.starts[i].After(idx.ends[j]) {
+				f(idx.positions[i], idx.positions[j])
+			}
+		}
+	}
+}
+
+// Find the record in the time range of t
+// Returns the position in the original slice, or -1 if not found
+// If the time ranges overlap, the first record
+// in the original slice is returned, same as the linear scan
+func (idx *intervalIndex) find(t time.Time) int {
+	// Binary search: n is the number of records starting at or before t
+	lo, hi := 0, len(idx.starts)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		if idx.starts[m].After(t) {
+			hi = m
+		} else {
+			lo = m + 1
+		}
+	}
+	n := lo
+	if !idx.overlapping {
+		if n > 0 && t.Compare(idx.ends[n-1]) <= 0 {
+			return idx.positions[n-1]
+		}
+		return -1
+	}
+	found := -1
+	for i := 0; i < n; i++ {
+		if t.Compare(idx.ends[i]) <= 0 &&
+			(found < 0 || idx.positions[i] < found) {
+			found = idx.positions[i]
+		}
+	}
+	return found
+}
+
+// Time-ranged records by callsign and their interval indexes
+type intervalTable[T timeRanged] struct {
+	// The table of the Database, e.g., MapException
+	records map[string][]T
+	// Indexes of the keys with many records
+	indexes map[string]*intervalIndex
+}
+
+// Build the interval indexes of a table
+func newIntervalTable[T timeRanged](m map[string][]T) intervalTable[T] {
+	it := intervalTable[T]{
+		records: m,
+		indexes: make(map[string]*intervalIndex),
+	}
+	for key, records := range m {
+		if len(records) >= intervalIndexMinRecords {
+			it.indexes[key] = newIntervalIndex(records)
+		}
+	}
+	return it
+}
+
+// Find the record of the key in the time range of t
+// The records of the key are recorded into lc if not nil
+// Returns the record and bool
+// If bool is true, the match exists; if false, did not matched
+func (it *intervalTable[T]) find(key string, t time.Time, lc *lookupContext) (T, bool) {
+	var zero T
+	records, exists := it.records[key]
+	if !exists {
+		return zero, false
+	}
+	if lc != nil {
+		narrowRecords(lc, t, records)
+	}
+	idx, indexed := it.indexes[key]
+	if !indexed {
+		// Scan the few records to find out whether the matching period exists
+		// Return the first matched result
+		for _, s := range records {
+			start, end := s.timeRange()
+			if timeInRange(t, start, end) {
+				return s, true
+			}
+		}
+		return zero, false
+	}
+	p := idx.find(t)
+	if p < 0 {
+		return zero, false
+	}
+	return records[p], true
+}
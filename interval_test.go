@@ -0,0 +1,145 @@
+package gocldb
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+// Base time of the test records
+var intervalBase = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Returns the time of the given seconds from intervalBase
+func at(sec int) time.Time {
+	return intervalBase.Add(time.Duration(sec) * time.Second)
+}
+
+// Returns the invalid operation records of the time ranges
+// given as pairs of seconds from intervalBase
+func intervalRecords(ranges ...[2]int) []CLDInvalid {
+	records := make([]CLDInvalid, len(ranges))
+	for i, r := range ranges {
+		records[i] = CLDInvalid{Record: uint64(i + 1), Start: at(r[0]), End: at(r[1])}
+	}
+	return records
+}
+
+// Former linear scan: the first record in the time range of t
+func linearFind(records []CLDInvalid, t time.Time) int {
+	for i, r := range records {
+		if timeInRange(t, r.Start, r.End) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestIntervalIndexFind(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	var random [][2]int
+	for range 40 {
+		start := rng.IntN(200)
+		random = append(random, [2]int{start, start + rng.IntN(30)})
+	}
+	tests := []struct {
+		name        string
+		records     []CLDInvalid
+		overlapping bool
+	}{
+		{"disjoint", intervalRecords(
+			[2]int{90, 99}, [2]int{0, 9}, [2]int{20, 29}, [2]int{10, 19},
+			[2]int{40, 49}, [2]int{30, 39}, [2]int{60, 69}, [2]int{50, 59},
+			[2]int{80, 89}, [2]int{70, 79}), false},
+		{"disjoint with gaps", intervalRecords(
+			[2]int{0, 0}, [2]int{2, 2}, [2]int{4, 5}, [2]int{8, 12},
+			[2]int{14, 14}, [2]int{20, 30}, [2]int{31, 31}, [2]int{40, 50},
+			[2]int{52, 60}), false},
+		// End of a record is the start of the next one
+		{"touching", intervalRecords(
+			[2]int{0, 10}, [2]int{10, 20}, [2]int{20, 30}, [2]int{30, 40},
+			[2]int{40, 50}, [2]int{50, 60}, [2]int{60, 70}, [2]int{70, 80},
+			[2]int{80, 90}), true},
+		{"nested", intervalRecords(
+			[2]int{0, 100}, [2]int{10, 20}, [2]int{30, 40}, [2]int{35, 38},
+			[2]int{50, 60}, [2]int{55, 120}, [2]int{70, 71}, [2]int{110, 130},
+			[2]int{140, 150}), true},
+		// The later record in the slice starts first
+		{"overlapping order", intervalRecords(
+			[2]int{50, 60}, [2]int{40, 70}, [2]int{30, 80}, [2]int{20, 90},
+			[2]int{10, 100}, [2]int{0, 110}, [2]int{55, 56}, [2]int{45, 65},
+			[2]int{0, 0}), true},
+		{"same start", intervalRecords(
+			[2]int{10, 20}, [2]int{10, 15}, [2]int{10, 30}, [2]int{10, 10},
+			[2]int{0, 5}, [2]int{40, 50}, [2]int{40, 45}, [2]int{60, 70},
+			[2]int{60, 60}), true},
+		{"random", intervalRecords(random...), true},
+	}
+	for _, tt := range tests {
+		if len(tt.records) < intervalIndexMinRecords {
+			t.Fatalf("%s: %d records not indexed", tt.name, len(tt.records))
+		}
+		idx := newIntervalIndex(tt.records)
+		if idx.overlapping != tt.overlapping {
+			t.Errorf("%s: overlapping = %v, want %v", tt.name, idx.overlapping, tt.overlapping)
+		}
+		// All the seconds including the boundary instants,
+		// and the instants just inside and outside of them
+		for sec := -2; sec <= 240; sec++ {
+			for _, d := range []time.Duration{-time.Nanosecond, 0, time.Nanosecond} {
+				qsotime := at(sec).Add(d)
+				if got, want := idx.find(qsotime), linearFind(tt.records, qsotime); got != want {
+					t.Errorf("%s: find(%v) = %d, want %d", tt.name, qsotime, got, want)
+				}
+			}
+		}
+
+		// Overlapping pairs are the same as the pairwise comparison
+		pairs := make(map[[2]int]bool)
+		idx.overlaps(func(i, j int) {
+			a, b := tt.records[i], tt.records[j]
+			if a.Start.Before(b.Start) {
+				t.Errorf("%s: overlaps(%d, %d): later record first", tt.name, i, j)
+			}
+			pairs[[2]int{min(i, j), max(i, j)}] = true
+		})
+		for i := range tt.records {
+			for j := i + 1; j < len(tt.records); j++ {
+				a, b := tt.records[i], tt.records[j]
+				want := !a.Start.After(b.End) && !b.Start.After(a.End)
+				if pairs[[2]int{i, j}] != want {
+					t.Errorf("%s: records %d and %d overlapping = %v, want %v",
+						tt.name, i, j, pairs[[2]int{i, j}], want)
+				}
+			}
+		}
+	}
+}
+
+// The table finds the same records through the index and the linear scan
+func TestIntervalTableFind(t *testing.T) {
+	m := map[string][]CLDInvalid{
+		"FEW": intervalRecords([2]int{0, 10}, [2]int{20, 30}),
+		"MANY": intervalRecords(
+			[2]int{0, 10}, [2]int{20, 30}, [2]int{25, 40}, [2]int{50, 60},
+			[2]int{70, 80}, [2]int{90, 100}, [2]int{110, 120}, [2]int{130, 140}),
+	}
+	it := newIntervalTable(m)
+	if _, indexed := it.indexes["MANY"]; !indexed {
+		t.Fatal("MANY not indexed")
+	}
+	if _, indexed := it.indexes["FEW"]; indexed {
+		t.Fatal("FEW indexed")
+	}
+	for key, records := range m {
+		for sec := -1; sec <= 141; sec++ {
+			r, found := it.find(key, at(sec), nil)
+			want := linearFind(records, at(sec))
+			if found != (want >= 0) || (found && r != records[want]) {
+				t.Errorf("find(%s, %d) = %v %v, want %d", key, sec, r.Record, found, want)
+			}
+		}
+	}
+	if _, found := it.find("NONE", at(0), nil); found {
+		t.Error("find(NONE) found")
+	}
+}
@@ -0,0 +1,154 @@
+// gocldb structured logging with log/slog
+
+package gocldb
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+)
+
+// Option of NewDatabase() and NewWatcher()
+type Option func(*Database)
+
+// Use logger for the messages of the Database
+// The lookup messages are written at slog.LevelDebug
+func WithLogger(logger *slog.Logger) Option {
+	return func(db *Database) {
+		if logger != nil {
+			db.logger = logger
+		}
+	}
+}
+
+// Use handler for the messages of the Database
+// See WithLogger()
+func WithHandler(handler slog.Handler) Option {
+	return func(db *Database) {
+		if handler != nil {
+			db.logger = slog.New(handler)
+		}
+	}
+}
+
+// Logger set by SetLogger() (nil if not set)
+var defaultLogger atomic.Pointer[slog.Logger]
+
+// Set the logger of the Databases created after this call
+// without WithLogger() or WithHandler(),
+// including those of the package-level loaders such as LoadCtyXml()
+// Set logger to nil to use DebugLogger again
+func SetLogger(logger *slog.Logger) {
+	defaultLogger.Store(logger)
+}
+
+// Returns the logger for a new Database
+// If SetLogger() is not called, the messages are written to DebugLogger,
+// which discards the output as default
+func newDefaultLogger() *slog.Logger {
+	if logger := defaultLogger.Load(); logger != nil {
+		return logger
+	}
+	return slog.New(newDebugLoggerHandler(DebugLogger))
+}
+
+// Logger discarding all messages
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// Returns the logger of db
+// A Database not created by NewDatabase() discards the messages
+func (db *Database) Logger() *slog.Logger {
+	if db.logger == nil {
+		return discardLogger
+	}
+	return db.logger
+}
+
+// Key of the trace ID in a context
+type traceIDKey struct{}
+
+// Returns a copy of ctx with the trace ID
+// The debug messages of a lookup with the context
+// have the trace ID in the trace_id attribute,
+// e.g., to follow a request of a server
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// Returns the trace ID of ctx set by WithTraceID()
+func TraceID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(traceIDKey{}).(string)
+	return id, ok
+}
+
+// Sequence number of the lookups without a trace ID
+var traceCounter atomic.Uint64
+
+// Set up lc for the debug messages of a lookup if enabled
+// A lookup without the trace ID in ctx is numbered
+// Returns lc, allocated if nil
+func (db *Database) traceLookup(ctx context.Context, lc *lookupContext) *lookupContext {
+	logger := db.Logger()
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		return lc
+	}
+	if lc == nil {
+		lc = newLookupContext()
+	}
+	id, ok := TraceID(ctx)
+	if !ok {
+		id = strconv.FormatUint(traceCounter.Add(1), 10)
+	}
+	lc.ctx = ctx
+	lc.logger = logger.With(slog.String("trace_id", id))
+	return lc
+}
+
+// slog.Handler writing the text format to a deprecated *log.Logger
+// Enabled only if the output of the logger is not discarded,
+// so the output can be enabled by SetOutput() after loading
+type debugLoggerHandler struct {
+	slog.Handler
+	logger *log.Logger
+}
+
+func newDebugLoggerHandler(logger *log.Logger) *debugLoggerHandler {
+	return &debugLoggerHandler{
+		Handler: slog.NewTextHandler(debugLoggerWriter{logger}, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+			// The logger writes the time
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if len(groups) == 0 && a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}),
+		logger: logger,
+	}
+}
+
+func (h *debugLoggerHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.logger.Writer() != io.Discard
+}
+
+func (h *debugLoggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &debugLoggerHandler{Handler: h.Handler.WithAttrs(attrs), logger: h.logger}
+}
+
+func (h *debugLoggerHandler) WithGroup(name string) slog.Handler {
+	return &debugLoggerHandler{Handler: h.Handler.WithGroup(name), logger: h.logger}
+}
+
+// io.Writer writing each line to a *log.Logger
+type debugLoggerWriter struct {
+	logger *log.Logger
+}
+
+func (w debugLoggerWriter) Write(p []byte) (int, error) {
+	err := w.logger.Output(2, string(p))
+	return len(p), err
+}
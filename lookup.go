@@ -0,0 +1,119 @@
+// gocldb lookup context threaded through a callsign check
+
+package gocldb
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Details of a callsign check
+// The check functions take a nil *lookupContext
+// when the details are not needed
+type lookupContext struct {
+	// Time range where the same records are in effect,
+	// i.e., the result is the same for any QSO time in the range
+	start time.Time
+	end   time.Time
+	// True if recording the steps
+	explain bool
+	// Steps taken in order
+	steps []ExplainStep
+	// Context and logger with the trace ID of the lookup
+	// (nil if the debug messages are not written)
+	ctx    context.Context
+	logger *slog.Logger
+}
+
+// Returns a lookupContext with the widest time range
+func newLookupContext() *lookupContext {
+	return &lookupContext{start: minTime, end: maxTime}
+}
+
+// Narrow the time range of lc around t
+// so that the record of the time range between start and end
+// is either in effect or not in effect in the whole range
+func (lc *lookupContext) narrow(t time.Time, start time.Time, end time.Time) {
+	if lc == nil {
+		return
+	}
+	switch {
+	case t.Before(start):
+		// Not in effect until start
+		lc.end = minTimeOf(lc.end, start.Add(-time.Nanosecond))
+	case t.After(end):
+		// Not in effect after end
+		lc.start = maxTimeOf(lc.start, end.Add(time.Nanosecond))
+	default:
+		// In effect between start and end
+		lc.start = maxTimeOf(lc.start, start)
+		lc.end = minTimeOf(lc.end, end)
+	}
+}
+
+// True if the debug messages are written
+// Check this before building the attributes in the lookup path
+func (lc *lookupContext) debug() bool {
+	return lc != nil && lc.logger != nil
+}
+
+// Write a debug message with the attributes
+func (lc *lookupContext) log(msg string, args ...any) {
+	if !lc.debug() {
+		return
+	}
+	lc.logger.DebugContext(lc.ctx, msg, args...)
+}
+
+// Record a step if explaining
+// The strings are copied so that the callsigns built
+// in the lookup path do not escape to the heap
+func (lc *lookupContext) addStep(step ExplainStep) {
+	if lc == nil || !lc.explain {
+		return
+	}
+	lc.steps = append(lc.steps, ExplainStep{
+		Step:    strings.Clone(step.Step),
+		Call:    strings.Clone(step.Call),
+		NewCall: strings.Clone(step.NewCall),
+		Record:  step.Record,
+		Matched: step.Matched,
+		Detail:  strings.Clone(step.Detail),
+	})
+}
+
+// Rewrite a callsign or prefix by a SPECIAL RULE
+// and record the step if explaining
+// The rule is logged if the debug messages are written
+// Returns newcall
+func (lc *lookupContext) rewrite(call string, newcall string, rule string) string {
+	lc.addStep(ExplainStep{Step: StepSpecialRule, Call: call, NewCall: newcall, Matched: true, Detail: rule})
+	if lc.debug() {
+		lc.log("special rule", "rp", call, "newcall", newcall, "rule", rule)
+	}
+	return newcall
+}
+
+// Narrow the time range of lc with all the records checked at t
+func narrowRecords[T timeRanged](lc *lookupContext, t time.Time, records []T) {
+	for _, r := range records {
+		start, end := r.timeRange()
+		lc.narrow(t, start, end)
+	}
+}
+
+func minTimeOf(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTimeOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,126 @@
+// gocldb local overlay of team-specific records
+
+package gocldb
+
+import (
+	"time"
+)
+
+// An overlay is a Database loaded from a file of the same format
+// as cty.xml, containing only the records to add locally,
+// e.g., special-event and DXpedition callsigns not yet in Club Log:
+//
+//	<clublog date="2024-01-01T00:00:00+00:00">
+//	  <exceptions>
+//	    <exception record="1">...</exception>
+//	  </exceptions>
+//	  <prefixes>...</prefixes>
+//	  <invalid_operations>...</invalid_operations>
+//	  <zone_exceptions>...</zone_exceptions>
+//	</clublog>
+//
+// The records use the same elements as cty.xml;
+// entities in an overlay are ignored,
+// and the ADIF entity codes refer to the entities of Club Log
+//
+// Precedence of the overlay records over the Club Log records:
+//   - exceptions, invalid operations, and zone exceptions:
+//     an overlay record matching the callsign and the QSO time
+//     is used before the Club Log records
+//   - prefixes: the longest matched prefix is used
+//     from both tables; the overlay record is used
+//     if the lengths are the same
+//
+// CLDCheckResult.Overlay is set to true if an overlay record matched
+
+// Load an overlay file and set it to db.Overlay
+// The file may be gzip or zip compressed as cty.xml
+// Do not load into a Database used by other goroutines
+func (db *Database) LoadOverlayFile(filename string) error {
+	overlay := NewDatabase()
+	err := overlay.LoadCtyXmlFile(filename)
+	if err != nil {
+		return err
+	}
+	db.Overlay = overlay
+	return nil
+}
+
+// Load an overlay file on top of the default Database
+// and publish the result as the default Database
+// The tables of the current default Database are shared, not copied
+func LoadOverlayFile(filename string) error {
+	overlay := NewDatabase()
+	err := overlay.LoadCtyXmlFile(filename)
+	if err != nil {
+		return err
+	}
+	SetDefaultDatabase(DefaultDatabase().WithOverlay(overlay))
+	return nil
+}
+
+// Returns a shallow copy of db with the overlay set
+// The tables of db are shared, not copied
+// Set overlay to nil to remove the overlay
+func (db *Database) WithOverlay(overlay *Database) *Database {
+	nd := *db
+	nd.Overlay = overlay
+	return &nd
+}
+
+// Look up exceptions from the overlay first, then from db
+// Returns the matched record, true if from the overlay,
+// and true if matched
+func (db *Database) lookupException(call string, t time.Time, lc *lookupContext) (CLDException, bool, bool) {
+	if db.Overlay != nil {
+		s, exists := db.Overlay.inExceptionMap(call, t, lc)
+		if exists {
+			return s, true, true
+		}
+	}
+	s, exists := db.inExceptionMap(call, t, lc)
+	return s, false, exists
+}
+
+// Look up zone exceptions from the overlay first, then from db
+// Returns the matched record, true if from the overlay,
+// and true if matched
+func (db *Database) lookupZoneException(call string, t time.Time, lc *lookupContext) (CLDZoneException, bool, bool) {
+	if db.Overlay != nil {
+		s, exists := db.Overlay.inZoneExceptionMap(call, t, lc)
+		if exists {
+			return s, true, true
+		}
+	}
+	s, exists := db.inZoneExceptionMap(call, t, lc)
+	return s, false, exists
+}
+
+// Look up invalid operations from the overlay first, then from db
+// Returns the matched record, true if from the overlay,
+// and true if matched
+func (db *Database) lookupInvalid(call string, t time.Time, lc *lookupContext) (CLDInvalid, bool, bool) {
+	if db.Overlay != nil {
+		s, exists := db.Overlay.inInvalidMap(call, t, lc)
+		if exists {
+			return s, true, true
+		}
+	}
+	s, exists := db.inInvalidMap(call, t, lc)
+	return s, false, exists
+}
+
+// Look up the longest matched prefix from both the overlay and db
+// The overlay record is used if the lengths are the same
+// Returns the matched prefix, the matched record,
+// true if from the overlay, and true if matched
+func (db *Database) lookupPrefix(call string, t time.Time, lc *lookupContext) (string, CLDPrefix, bool, bool) {
+	p, s, exists := db.inPrefixMap(call, t, lc)
+	if db.Overlay != nil {
+		op, opm, oexists := db.Overlay.inPrefixMap(call, t, lc)
+		if oexists && len(op) >= len(p) {
+			return op, opm, true, true
+		}
+	}
+	return p, s, false, exists
+}
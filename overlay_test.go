@@ -0,0 +1,61 @@
+package gocldb
+
+import (
+	"testing"
+)
+
+// Records of this file take priority over testCtyXml
+const testOverlayXml = "testdata/overlay.xml"
+
+func TestOverlay(t *testing.T) {
+	base := loadTestDatabase(t)
+	db := base.WithOverlay(nil)
+	err := db.LoadOverlayFile(testOverlayXml)
+	if err != nil {
+		t.Fatalf("LoadOverlayFile(%q): %v", testOverlayXml, err)
+	}
+	if base.Overlay != nil {
+		t.Fatalf("WithOverlay() changed the original Database")
+	}
+
+	tests := []struct {
+		name    string
+		call    string
+		time    string
+		adif    uint16
+		cqz     uint8
+		invalid bool
+		overlay bool
+	}{
+		// Exceptions
+		{"exception in overlay", "JA1XYZ", "2019-01-15T00:00:00Z", 192, 27, false, true},
+		{"exception out of overlay range", "JA1XYZ", "2021-01-15T00:00:00Z", 192, 27, false, false},
+		{"exception only in base", "BS7H", "2020-06-01T00:00:00Z", 506, 27, false, false},
+		// Invalid operations
+		{"invalid in overlay", "JA1OVL", "2023-06-01T00:00:00Z", 0, 0, true, true},
+		{"invalid out of overlay range", "JA1OVL", "2024-06-01T00:00:00Z", 339, 25, false, false},
+		{"invalid only in base", "JA1INV", "2022-06-01T00:00:00Z", 0, 0, true, false},
+		// Zone exceptions
+		{"zone exception in overlay", "W1AW", "2023-01-15T00:00:00Z", 291, 3, false, true},
+		// Prefixes: the longest match of both
+		{"longer prefix in overlay", "JA1ZZZ", "2023-01-15T00:00:00Z", 192, 27, false, true},
+		{"longer prefix in base", "JD1MAB", "2023-01-15T00:00:00Z", 177, 27, false, false},
+		{"same length prefix", "JD1ABC", "2023-01-15T00:00:00Z", 177, 27, false, true},
+		{"prefix only in base", "JA2ABC", "2023-01-15T00:00:00Z", 339, 25, false, false},
+	}
+	for _, tt := range tests {
+		qsotime := mustTime(t, tt.time)
+		result, _ := db.CheckCallsign(tt.call, qsotime)
+		if result.Invalid != tt.invalid || result.Overlay != tt.overlay ||
+			(!tt.invalid && (result.Adif != tt.adif || result.Cqz != tt.cqz)) {
+			t.Errorf("%s: CheckCallsign(%s) = adif %d cqz %d invalid %t overlay %t, want %d %d %t %t",
+				tt.name, tt.call, result.Adif, result.Cqz, result.Invalid, result.Overlay,
+				tt.adif, tt.cqz, tt.invalid, tt.overlay)
+		}
+		// The base Database is not changed
+		bresult, _ := base.CheckCallsign(tt.call, qsotime)
+		if bresult.Overlay {
+			t.Errorf("%s: CheckCallsign(%s) without overlay: Overlay set", tt.name, tt.call)
+		}
+	}
+}
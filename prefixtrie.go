@@ -0,0 +1,104 @@
+// gocldb prefix trie for the longest-match prefix lookup
+
+package gocldb
+
+import (
+	"time"
+)
+
+// A node of the prefix trie
+// Each node corresponds to a string of the path from the root
+type prefixTrieNode struct {
+	// Child nodes by the next character
+	children map[byte]*prefixTrieNode
+	// Map key of CLDMapPrefix if the path is a prefix
+	prefix string
+	// Records of the prefix (nil if the path is not a prefix)
+	entries []CLDPrefix
+	// Nearest ancestor node which is a prefix,
+	// i.e., the next shorter prefix to try
+	shorter *prefixTrieNode
+}
+
+// Prefix trie indexing the keys of CLDMapPrefix
+type prefixTrie struct {
+	root prefixTrieNode
+}
+
+// Build a prefix trie from the prefix map
+// The record slices are shared with the map
+func newPrefixTrie(m map[string][]CLDPrefix) *prefixTrie {
+	t := &prefixTrie{}
+	for prefix, entries := range m {
+		n := &t.root
+		for i := 0; i < len(prefix); i++ {
+			c := prefix[i]
+			child, exists := n.children[c]
+			if !exists {
+				if n.children == nil {
+					n.children = make(map[byte]*prefixTrieNode)
+				}
+				child = &prefixTrieNode{}
+				n.children[c] = child
+			}
+			n = child
+		}
+		n.prefix = prefix
+		n.entries = entries
+	}
+	t.root.setShorter(nil)
+	return t
+}
+
+// Set the shorter links of n and its descendants
+// shorter is the nearest prefix node above n
+func (n *prefixTrieNode) setShorter(shorter *prefixTrieNode) {
+	n.shorter = shorter
+	if n.entries != nil {
+		shorter = n
+	}
+	for _, child := range n.children {
+		child.setShorter(shorter)
+	}
+}
+
+// Find the longest prefix of call with a record
+// in the time range of t
+// Only the characters of call are walked,
+// then shorter prefixes are tried if the time range does not match
+// The records of the tried prefixes are recorded into lc if not nil
+// Returns the matched prefix, corresponding CLDPrefix, and bool
+// If bool is true, the match exists; if false, did not matched
+func (t *prefixTrie) lookup(call string, qsotime time.Time, lc *lookupContext) (string, CLDPrefix, bool) {
+	// Find the longest prefix node on the path of call
+	var longest *prefixTrieNode
+	n := &t.root
+	for i := 0; i < len(call); i++ {
+		child, exists := n.children[call[i]]
+		if !exists {
+			break
+		}
+		n = child
+		if n.entries != nil {
+			longest = n
+		}
+	}
+	// Search if a matched time entry exists in a prefix
+	// from the longer to the shorter ones
+	for p := longest; p != nil; p = p.shorter {
+		if lc != nil {
+			narrowRecords(lc, qsotime, p.entries)
+		}
+		for _, s := range p.entries {
+			if timeInRange(qsotime, s.Start, s.End) {
+				lc.addStep(ExplainStep{Step: StepPrefix, Call: p.prefix, Record: s.Record, Matched: true})
+				return p.prefix, s, true
+			}
+		}
+		lc.addStep(ExplainStep{Step: StepPrefix, Call: p.prefix, Matched: false, Detail: "out of time range"})
+	}
+	if longest == nil {
+		lc.addStep(ExplainStep{Step: StepPrefix, Call: call, Matched: false, Detail: "no prefix"})
+	}
+	return "", CLDPrefix{}, false
+}
@@ -0,0 +1,151 @@
+package gocldb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Former linear scan of inPrefixMap() as the reference:
+// try all the prefixes of call from the longest one
+func linearPrefixLookup(m map[string][]CLDPrefix, call string, t time.Time) (string, CLDPrefix, bool) {
+	matched := make(map[int]string, 4)
+	ml := 0
+	for p := range m {
+		if strings.HasPrefix(call, p) {
+			matched[len(p)] = p
+			ml = max(ml, len(p))
+		}
+	}
+	for i := ml; i > 0; i-- {
+		p, exists := matched[i]
+		if !exists {
+			continue
+		}
+		for _, s := range m[p] {
+			if timeInRange(t, s.Start, s.End) {
+				return p, s, true
+			}
+		}
+	}
+	return "", CLDPrefix{}, false
+}
+
+func TestPrefixTrieLookup(t *testing.T) {
+	db := loadTestDatabase(t)
+	in2000 := mustTime(t, "2000-06-01T00:00:00Z")
+	in2023 := mustTime(t, "2023-01-15T00:00:00Z")
+	tests := []struct {
+		call   string
+		t      time.Time
+		prefix string
+	}{
+		{"JA1ABC", in2023, "JA"},
+		{"JD1ABC", in2023, "JD1"},
+		{"JD1MAB", in2023, "JD1M"},
+		{"HK0MA", in2023, "HK0M"},
+		{"HK0A", in2023, "HK0"},
+		{"KG4AB", in2023, "KG4"},
+		{"KG1AB", in2023, "K"},
+		{"3D2/C", in2023, "3D2/C"},
+		{"3D2/X", in2023, "3D2"},
+		// KL7 is in the time range only in 2000-2001
+		{"KL7ABC", in2000, "KL7"},
+		{"KL7ABC", in2023, "KL"},
+		{"KL", in2023, "KL"},
+		{"UA9VAA", in2023, "UA9V"},
+		{"UA9AA", in2023, "UA9"},
+		{"UA1AA", in2023, "UA"},
+		// No prefix
+		{"QQ1ABC", in2023, ""},
+		{"", in2023, ""},
+	}
+	for _, tt := range tests {
+		p, s, found := db.prefixTrie.lookup(tt.call, tt.t, nil)
+		if p != tt.prefix || found != (tt.prefix != "") {
+			t.Errorf("lookup(%q, %v) = %q, %v, want %q", tt.call, tt.t, p, found, tt.prefix)
+		}
+		lp, ls, lfound := linearPrefixLookup(db.MapPrefix, tt.call, tt.t)
+		if p != lp || s.Record != ls.Record || found != lfound {
+			t.Errorf("lookup(%q, %v) = %q %d %v, linear scan = %q %d %v",
+				tt.call, tt.t, p, s.Record, found, lp, ls.Record, lfound)
+		}
+	}
+}
+
+// The trie and the linear scan must agree on every prefix
+// with several suffixes and times, including the fallback
+// to the shorter prefixes and the records of multiple time ranges
+func TestPrefixTrieLinearScan(t *testing.T) {
+	db := loadTestDatabase(t)
+	// Records of multiple time ranges with a gap in 2005
+	db.MapPrefix["AB"] = []CLDPrefix{
+		{Record: 900, Adif: 1, Start: minTime, End: mustTime(t, "2004-12-31T23:59:59Z")},
+		{Record: 901, Adif: 2, Start: mustTime(t, "2006-01-01T00:00:00Z"), End: maxTime},
+	}
+	db.MapPrefix["AB1"] = []CLDPrefix{
+		{Record: 902, Adif: 3, Start: mustTime(t, "2003-01-01T00:00:00Z"), End: mustTime(t, "2003-12-31T23:59:59Z")},
+	}
+	db.BuildIndex()
+
+	times := []time.Time{
+		minTime,
+		mustTime(t, "1999-12-31T23:59:59Z"),
+		mustTime(t, "2000-01-01T00:00:00Z"),
+		mustTime(t, "2001-12-31T23:59:59Z"),
+		mustTime(t, "2002-01-01T00:00:00Z"),
+		mustTime(t, "2003-06-01T00:00:00Z"),
+		mustTime(t, "2005-06-01T00:00:00Z"),
+		mustTime(t, "2023-01-15T00:00:00Z"),
+	}
+	for prefix := range db.MapPrefix {
+		calls := []string{prefix, prefix + "A", prefix + "1ABC", prefix[:len(prefix)-1]}
+		for _, call := range calls {
+			for _, qsotime := range times {
+				p, s, found := db.prefixTrie.lookup(call, qsotime, nil)
+				lp, ls, lfound := linearPrefixLookup(db.MapPrefix, call, qsotime)
+				if p != lp || s.Record != ls.Record || found != lfound {
+					t.Errorf("lookup(%q, %v) = %q %d %v, linear scan = %q %d %v",
+						call, qsotime, p, s.Record, found, lp, ls.Record, lfound)
+				}
+			}
+		}
+	}
+}
+
+// Calls of the generated cty.xml for the prefix benchmarks
+var benchPrefixCalls = []string{
+	"YAA0ABC", "YMN1XY", "YZZ4A", "YKT3/P", "YBC2AAA", "QQ1ABC",
+}
+
+// Returns the Database of the generated cty.xml
+func loadBenchDatabase(b *testing.B) *Database {
+	b.Helper()
+	db := NewDatabase()
+	err := db.LoadCtyXmlReader(bytes.NewReader(benchCtyXml))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return db
+}
+
+func BenchmarkPrefixTrie(b *testing.B) {
+	db := loadBenchDatabase(b)
+	qsotime := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		db.prefixTrie.lookup(benchPrefixCalls[i%len(benchPrefixCalls)], qsotime, nil)
+	}
+}
+
+func BenchmarkPrefixLinearScan(b *testing.B) {
+	db := loadBenchDatabase(b)
+	qsotime := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		linearPrefixLookup(db.MapPrefix, benchPrefixCalls[i%len(benchPrefixCalls)], qsotime)
+	}
+}
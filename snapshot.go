@@ -0,0 +1,539 @@
+// gocldb precompiled binary snapshot of the Database
+
+// Snapshot file format (integers in little endian):
+//
+//	magic     6 bytes  "GOCLDB"
+//	version   uint16   SnapshotVersion
+//	date      int64    cty.xml release date and time (Unix seconds)
+//	length    uint64   payload length in bytes
+//	checksum  32 bytes SHA-256 of the payload
+//	payload   tables of the Database
+//
+// In the payload, integers are varints, strings are
+// uvarint length + bytes, times are varint Unix seconds,
+// and float64 values are 8-byte IEEE 754 bits.
+// Map keys are written in sorted order
+// so that the same cty.xml produces the same snapshot.
+
+package gocldb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"time"
+)
+
+// Snapshot format version
+// Snapshots of other versions are rejected
+// Version 2: entity continents are set
+const SnapshotVersion = 2
+
+// Magic bytes of the snapshot file
+var snapshotMagic = [6]byte{'G', 'O', 'C', 'L', 'D', 'B'}
+
+// Errors
+var ErrSnapshotFormat = errors.New("invalid snapshot format")
+var ErrSnapshotVersion = errors.New("unsupported snapshot version")
+var ErrSnapshotChecksum = errors.New("snapshot checksum mismatch")
+var ErrSnapshotStale = errors.New("snapshot is older than cty.xml")
+
+// Snapshot file header
+type snapshotHeader struct {
+	Magic    [6]byte
+	Version  uint16
+	Date     int64
+	Length   uint64
+	Checksum [sha256.Size]byte
+}
+
+// Write the tables of db as a snapshot to w
+func (db *Database) WriteSnapshot(w io.Writer) error {
+	var e snapshotEncoder
+
+	putTable(&e, db.MapEntity, (*snapshotEncoder).putEntity)
+	e.putUvarint(uint64(len(db.MapEntityByAdif)))
+	adifs := make([]uint16, 0, len(db.MapEntityByAdif))
+	for adif := range db.MapEntityByAdif {
+		adifs = append(adifs, adif)
+	}
+	slices.Sort(adifs)
+	for _, adif := range adifs {
+		e.putUvarint(uint64(adif))
+		e.putEntityByAdif(db.MapEntityByAdif[adif])
+	}
+	putTable(&e, db.MapException, (*snapshotEncoder).putException)
+	putTable(&e, db.MapPrefix, (*snapshotEncoder).putPrefix)
+	putTable(&e, db.MapInvalid, (*snapshotEncoder).putInvalid)
+	putTable(&e, db.MapZoneException, (*snapshotEncoder).putZoneException)
+
+	payload := e.buf.Bytes()
+	header := snapshotHeader{
+		Magic:    snapshotMagic,
+		Version:  SnapshotVersion,
+		Date:     db.VersionDateTime.Unix(),
+		Length:   uint64(len(payload)),
+		Checksum: sha256.Sum256(payload),
+	}
+	err := binary.Write(w, binary.LittleEndian, &header)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(payload)
+	return err
+}
+
+// Write the tables of db as a snapshot file of filename
+// The file is replaced by an atomic rename
+func (db *Database) WriteSnapshotFile(filename string) error {
+	var buf bytes.Buffer
+	err := db.WriteSnapshot(&buf)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, buf.Bytes())
+}
+
+// Read the snapshot header from r
+func readSnapshotHeader(r io.Reader) (snapshotHeader, error) {
+	var header snapshotHeader
+	err := binary.Read(r, binary.LittleEndian, &header)
+	if err != nil {
+		return header, fmt.Errorf("%w: %w", ErrSnapshotFormat, err)
+	}
+	if header.Magic != snapshotMagic {
+		return header, ErrSnapshotFormat
+	}
+	if header.Version != SnapshotVersion {
+		return header, fmt.Errorf("%w: %d", ErrSnapshotVersion, header.Version)
+	}
+	if header.Length > MaxCtyXmlSize {
+		return header, ErrTooLarge
+	}
+	return header, nil
+}
+
+// Read a snapshot from r and load it into db
+// The tables of db are replaced only if succeeded
+// Do not load into a Database used by other goroutines
+func (db *Database) LoadSnapshotReader(r io.Reader) error {
+	header, err := readSnapshotHeader(r)
+	if err != nil {
+		return err
+	}
+	return db.loadSnapshotPayload(r, header)
+}
+
+// Read the snapshot file of filename and load it into db
+// The path of the loaded file is set to db.Source
+func (db *Database) LoadSnapshotFile(filename string) error {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	err = db.LoadSnapshotReader(fp)
+	if err != nil {
+		return err
+	}
+	db.Source = filename
+	return nil
+}
+
+// Load the snapshot file of snapshot into db
+// if it is not older than cty.xml located from the given list
+// of file paths (or CtyXmlSearchPath() if no path is given);
+// otherwise load the cty.xml file
+// The snapshot is used if cty.xml is not found,
+// and the embedded copy is used if neither is available
+// and no path is given
+func (db *Database) LoadSnapshotOrCtyXml(snapshot string, filenames ...string) error {
+	xmlfile, xerr := FindCtyXml(filenames...)
+	serr := db.loadFreshSnapshot(snapshot, xmlfile, xerr == nil)
+	if serr == nil {
+		return nil
+	}
+	db.Logger().Debug("LoadSnapshotOrCtyXml(): snapshot not used", "file", snapshot, "error", serr)
+	if xerr != nil {
+		if len(filenames) == 0 && db.fallbackEmbedded(xerr) == nil {
+			return nil
+		}
+		return errors.Join(serr, xerr)
+	}
+	return db.LoadCtyXmlFile(xmlfile)
+}
+
+// Load cty.xml or its snapshot into a new Database,
+// then publish it as the default Database
+// See (*Database).LoadSnapshotOrCtyXml()
+func LoadSnapshotOrCtyXml(snapshot string, filenames ...string) error {
+	db := NewDatabase()
+	err := db.LoadSnapshotOrCtyXml(snapshot, filenames...)
+	if err != nil {
+		return err
+	}
+	SetDefaultDatabase(db)
+	return nil
+}
+
+// Load the snapshot file into db
+// if not older than the cty.xml file of xmlfile (if checkxml is true)
+func (db *Database) loadFreshSnapshot(snapshot string, xmlfile string, checkxml bool) error {
+	fp, err := os.Open(snapshot)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	header, err := readSnapshotHeader(fp)
+	if err != nil {
+		return err
+	}
+	if checkxml {
+		xmldate, err := ReadCtyXmlFileDate(xmlfile)
+		if err != nil {
+			return err
+		}
+		if xmldate.Unix() > header.Date {
+			return ErrSnapshotStale
+		}
+	}
+	err = db.loadSnapshotPayload(fp, header)
+	if err != nil {
+		return err
+	}
+	db.Source = snapshot
+	return nil
+}
+
+// Read and verify the snapshot payload from r
+// and load it into db
+func (db *Database) loadSnapshotPayload(r io.Reader, header snapshotHeader) error {
+	payload := make([]byte, header.Length)
+	_, err := io.ReadFull(r, payload)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSnapshotFormat, err)
+	}
+	if sha256.Sum256(payload) != header.Checksum {
+		return ErrSnapshotChecksum
+	}
+
+	d := snapshotDecoder{buf: payload, str: string(payload)}
+	// The tables are allocated by the decoder in their sizes
+	nd := &Database{}
+	nd.MapEntity = getTable(&d, (*snapshotDecoder).getEntity)
+	n := d.getCount()
+	nd.MapEntityByAdif = make(map[uint16]CLDEntityByAdif, n)
+	for i := 0; i < n; i++ {
+		adif := uint16(d.getUvarint())
+		nd.MapEntityByAdif[adif] = d.getEntityByAdif()
+	}
+	nd.MapException = getTable(&d, (*snapshotDecoder).getException)
+	nd.MapPrefix = getTable(&d, (*snapshotDecoder).getPrefix)
+	nd.MapInvalid = getTable(&d, (*snapshotDecoder).getInvalid)
+	nd.MapZoneException = getTable(&d, (*snapshotDecoder).getZoneException)
+	if d.err != nil {
+		return d.err
+	}
+	if d.pos != len(d.buf) {
+		return ErrSnapshotFormat
+	}
+
+	nd.VersionDateTime = time.Unix(header.Date, 0).UTC()
+	db.replaceTables(nd)
+
+	return nil
+}
+
+// Snapshot payload encoder
+type snapshotEncoder struct {
+	buf     bytes.Buffer
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func (e *snapshotEncoder) putUvarint(v uint64) {
+	n := binary.PutUvarint(e.scratch[:], v)
+	e.buf.Write(e.scratch[:n])
+}
+
+func (e *snapshotEncoder) putVarint(v int64) {
+	n := binary.PutVarint(e.scratch[:], v)
+	e.buf.Write(e.scratch[:n])
+}
+
+func (e *snapshotEncoder) putString(s string) {
+	e.putUvarint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+func (e *snapshotEncoder) putBool(b bool) {
+	if b {
+		e.buf.WriteByte(1)
+	} else {
+		e.buf.WriteByte(0)
+	}
+}
+
+func (e *snapshotEncoder) putFloat(f float64) {
+	binary.LittleEndian.PutUint64(e.scratch[:8], math.Float64bits(f))
+	e.buf.Write(e.scratch[:8])
+}
+
+func (e *snapshotEncoder) putTime(t time.Time) {
+	e.putVarint(t.Unix())
+}
+
+// Write a map of slices in the sorted key order
+func putTable[T any](e *snapshotEncoder, m map[string][]T, put func(*snapshotEncoder, *T)) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	e.putUvarint(uint64(len(keys)))
+	for _, k := range keys {
+		e.putString(k)
+		s := m[k]
+		e.putUvarint(uint64(len(s)))
+		for i := range s {
+			put(e, &s[i])
+		}
+	}
+}
+
+func (e *snapshotEncoder) putEntity(d *CLDEntity) {
+	e.putUvarint(uint64(d.Adif))
+	e.putString(d.Name)
+	e.putBool(d.Deleted)
+	e.putUvarint(uint64(d.Cqz))
+	e.putString(d.Cont)
+	e.putFloat(d.Long)
+	e.putFloat(d.Lat)
+	e.putTime(d.Start)
+	e.putTime(d.End)
+	e.putBool(d.Whitelist)
+	e.putTime(d.WhitelistStart)
+	e.putTime(d.WhitelistEnd)
+}
+
+func (e *snapshotEncoder) putEntityByAdif(d CLDEntityByAdif) {
+	e.putString(d.Name)
+	e.putString(d.Prefix)
+	e.putBool(d.Deleted)
+	e.putUvarint(uint64(d.Cqz))
+	e.putString(d.Cont)
+	e.putFloat(d.Long)
+	e.putFloat(d.Lat)
+	e.putTime(d.Start)
+	e.putTime(d.End)
+	e.putBool(d.Whitelist)
+	e.putTime(d.WhitelistStart)
+	e.putTime(d.WhitelistEnd)
+}
+
+func (e *snapshotEncoder) putException(d *CLDException) {
+	e.putUvarint(d.Record)
+	e.putString(d.Entity)
+	e.putUvarint(uint64(d.Adif))
+	e.putUvarint(uint64(d.Cqz))
+	e.putString(d.Cont)
+	e.putFloat(d.Long)
+	e.putFloat(d.Lat)
+	e.putTime(d.Start)
+	e.putTime(d.End)
+}
+
+func (e *snapshotEncoder) putPrefix(d *CLDPrefix) {
+	e.putUvarint(d.Record)
+	e.putString(d.Entity)
+	e.putUvarint(uint64(d.Adif))
+	e.putUvarint(uint64(d.Cqz))
+	e.putString(d.Cont)
+	e.putFloat(d.Long)
+	e.putFloat(d.Lat)
+	e.putTime(d.Start)
+	e.putTime(d.End)
+}
+
+func (e *snapshotEncoder) putInvalid(d *CLDInvalid) {
+	e.putUvarint(d.Record)
+	e.putTime(d.Start)
+	e.putTime(d.End)
+}
+
+func (e *snapshotEncoder) putZoneException(d *CLDZoneException) {
+	e.putUvarint(d.Record)
+	e.putUvarint(uint64(d.Zone))
+	e.putTime(d.Start)
+	e.putTime(d.End)
+}
+
+// Snapshot payload decoder
+// The first error is kept in err and the following reads return zero values
+type snapshotDecoder struct {
+	// Payload as bytes
+	buf []byte
+	// Payload as a string sharing the memory of decoded strings
+	str string
+	pos int
+	err error
+}
+
+func (d *snapshotDecoder) fail() {
+	if d.err == nil {
+		d.err = ErrSnapshotFormat
+	}
+	d.pos = len(d.buf)
+}
+
+func (d *snapshotDecoder) getUvarint() uint64 {
+	v, n := binary.Uvarint(d.buf[d.pos:])
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+func (d *snapshotDecoder) getVarint() int64 {
+	v, n := binary.Varint(d.buf[d.pos:])
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+// Read a count of the following elements,
+// bounded by the remaining payload length
+func (d *snapshotDecoder) getCount() int {
+	v := d.getUvarint()
+	if v > uint64(len(d.buf)-d.pos) {
+		d.fail()
+		return 0
+	}
+	return int(v)
+}
+
+func (d *snapshotDecoder) getString() string {
+	l := d.getCount()
+	s := d.str[d.pos : d.pos+l]
+	d.pos += l
+	return s
+}
+
+func (d *snapshotDecoder) getBool() bool {
+	if d.pos >= len(d.buf) {
+		d.fail()
+		return false
+	}
+	b := d.buf[d.pos]
+	d.pos++
+	return b != 0
+}
+
+func (d *snapshotDecoder) getFloat() float64 {
+	if d.pos+8 > len(d.buf) {
+		d.fail()
+		return 0
+	}
+	v := binary.LittleEndian.Uint64(d.buf[d.pos:])
+	d.pos += 8
+	return math.Float64frombits(v)
+}
+
+func (d *snapshotDecoder) getTime() time.Time {
+	return time.Unix(d.getVarint(), 0).UTC()
+}
+
+// Read a map of slices
+func getTable[T any](d *snapshotDecoder, get func(*snapshotDecoder, *T)) map[string][]T {
+	n := d.getCount()
+	m := make(map[string][]T, n)
+	for i := 0; i < n; i++ {
+		k := d.getString()
+		l := d.getCount()
+		s := make([]T, l)
+		for j := range s {
+			get(d, &s[j])
+		}
+		m[k] = s
+	}
+	return m
+}
+
+func (d *snapshotDecoder) getEntity(v *CLDEntity) {
+	v.Adif = uint16(d.getUvarint())
+	v.Name = d.getString()
+	v.Deleted = d.getBool()
+	v.Cqz = uint8(d.getUvarint())
+	v.Cont = d.getString()
+	v.Long = d.getFloat()
+	v.Lat = d.getFloat()
+	v.Start = d.getTime()
+	v.End = d.getTime()
+	v.Whitelist = d.getBool()
+	v.WhitelistStart = d.getTime()
+	v.WhitelistEnd = d.getTime()
+}
+
+func (d *snapshotDecoder) getEntityByAdif() CLDEntityByAdif {
+	var v CLDEntityByAdif
+	v.Name = d.getString()
+	v.Prefix = d.getString()
+	v.Deleted = d.getBool()
+	v.Cqz = uint8(d.getUvarint())
+	v.Cont = d.getString()
+	v.Long = d.getFloat()
+	v.Lat = d.getFloat()
+	v.Start = d.getTime()
+	v.End = d.getTime()
+	v.Whitelist = d.getBool()
+	v.WhitelistStart = d.getTime()
+	v.WhitelistEnd = d.getTime()
+	return v
+}
+
+func (d *snapshotDecoder) getException(v *CLDException) {
+	v.Record = d.getUvarint()
+	v.Entity = d.getString()
+	v.Adif = uint16(d.getUvarint())
+	v.Cqz = uint8(d.getUvarint())
+	v.Cont = d.getString()
+	v.Long = d.getFloat()
+	v.Lat = d.getFloat()
+	v.Start = d.getTime()
+	v.End = d.getTime()
+}
+
+func (d *snapshotDecoder) getPrefix(v *CLDPrefix) {
+	v.Record = d.getUvarint()
+	v.Entity = d.getString()
+	v.Adif = uint16(d.getUvarint())
+	v.Cqz = uint8(d.getUvarint())
+	v.Cont = d.getString()
+	v.Long = d.getFloat()
+	v.Lat = d.getFloat()
+	v.Start = d.getTime()
+	v.End = d.getTime()
+}
+
+func (d *snapshotDecoder) getInvalid(v *CLDInvalid) {
+	v.Record = d.getUvarint()
+	v.Start = d.getTime()
+	v.End = d.getTime()
+}
+
+func (d *snapshotDecoder) getZoneException(v *CLDZoneException) {
+	v.Record = d.getUvarint()
+	v.Zone = uint8(d.getUvarint())
+	v.Start = d.getTime()
+	v.End = d.getTime()
+}
@@ -0,0 +1,176 @@
+package gocldb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Returns the snapshot of the test fixture
+func testSnapshot(t *testing.T) (*Database, []byte) {
+	t.Helper()
+	db := loadTestDatabase(t)
+	var buf bytes.Buffer
+	if err := db.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return db, buf.Bytes()
+}
+
+// Size of the snapshot header
+var snapshotHeaderSize = binary.Size(snapshotHeader{})
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	db, data := testSnapshot(t)
+	sdb := NewDatabase()
+	if err := sdb.LoadSnapshotReader(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	checkSameTables(t, sdb, db)
+
+	// The same tables produce the same snapshot
+	var buf bytes.Buffer
+	if err := sdb.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Error("snapshot of the loaded snapshot differs")
+	}
+
+	// Lookups give the same results
+	qsotime := mustTime(t, "2019-01-15T00:00:00Z")
+	for _, call := range []string{"JA1XYZ", "JA1ABC", "KL7ABC", "W1AW/2", "KG4AB"} {
+		r1, err1 := db.CheckCallsign(call, qsotime)
+		r2, err2 := sdb.CheckCallsign(call, qsotime)
+		if r1.Adif != r2.Adif || r1.Cqz != r2.Cqz || !errors.Is(err2, err1) {
+			t.Errorf("%s: snapshot %d %d %v, cty.xml %d %d %v",
+				call, r2.Adif, r2.Cqz, err2, r1.Adif, r1.Cqz, err1)
+		}
+	}
+}
+
+func TestSnapshotInvalid(t *testing.T) {
+	_, data := testSnapshot(t)
+	tests := []struct {
+		name   string
+		modify func([]byte)
+		err    error
+	}{
+		{"magic", func(b []byte) { b[0] = 'X' }, ErrSnapshotFormat},
+		{"version", func(b []byte) { b[6]++ }, ErrSnapshotVersion},
+		{"checksum", func(b []byte) { b[snapshotHeaderSize-1] ^= 1 }, ErrSnapshotChecksum},
+		{"payload", func(b []byte) { b[snapshotHeaderSize+10] ^= 1 }, ErrSnapshotChecksum},
+	}
+	for _, tt := range tests {
+		b := bytes.Clone(data)
+		tt.modify(b)
+		db := loadTestDatabase(t)
+		err := db.LoadSnapshotReader(bytes.NewReader(b))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.err)
+		}
+		if len(db.MapPrefix) == 0 {
+			t.Errorf("%s: tables replaced", tt.name)
+		}
+	}
+}
+
+// A truncated snapshot must fail without panic
+// and without changing the tables
+func TestSnapshotTruncated(t *testing.T) {
+	_, data := testSnapshot(t)
+	db := NewDatabase()
+	for n := range len(data) {
+		err := db.LoadSnapshotReader(bytes.NewReader(data[:n]))
+		if !errors.Is(err, ErrSnapshotFormat) {
+			t.Fatalf("truncated at %d: %v, want ErrSnapshotFormat", n, err)
+		}
+		if len(db.MapPrefix) != 0 {
+			t.Fatalf("truncated at %d: tables replaced", n)
+		}
+	}
+
+	// Truncated payloads with the valid checksums
+	// must be rejected by the decoder
+	var header snapshotHeader
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &header); err != nil {
+		t.Fatal(err)
+	}
+	payload := data[snapshotHeaderSize:]
+	for n := range len(payload) {
+		h := header
+		h.Length = uint64(n)
+		h.Checksum = sha256.Sum256(payload[:n])
+		err := db.loadSnapshotPayload(bytes.NewReader(payload[:n]), h)
+		if !errors.Is(err, ErrSnapshotFormat) {
+			t.Fatalf("payload truncated at %d: %v, want ErrSnapshotFormat", n, err)
+		}
+		if len(db.MapPrefix) != 0 {
+			t.Fatalf("payload truncated at %d: tables replaced", n)
+		}
+	}
+}
+
+func TestLoadSnapshotOrCtyXml(t *testing.T) {
+	db, data := testSnapshot(t)
+	dir := t.TempDir()
+	snapshot := filepath.Join(dir, "cty.cldb")
+	xmlfile := filepath.Join(dir, "cty.xml")
+	if err := os.WriteFile(snapshot, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	xml, err := os.ReadFile(testCtyXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(xmlfile, xml, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Snapshot of the same release
+	sdb := NewDatabase()
+	if err := sdb.LoadSnapshotOrCtyXml(snapshot, xmlfile); err != nil {
+		t.Fatal(err)
+	}
+	if sdb.Source != snapshot {
+		t.Errorf("Source = %q, want the snapshot %q", sdb.Source, snapshot)
+	}
+	checkSameTables(t, sdb, db)
+
+	// cty.xml is newer than the snapshot
+	newer := bytes.Replace(xml, []byte(`date="2024-01-10T12:00:00+00:00"`),
+		[]byte(`date="2024-02-10T12:00:00+00:00"`), 1)
+	if err := os.WriteFile(xmlfile, newer, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = NewDatabase().loadFreshSnapshot(snapshot, xmlfile, true)
+	if !errors.Is(err, ErrSnapshotStale) {
+		t.Errorf("loadFreshSnapshot(): %v, want ErrSnapshotStale", err)
+	}
+	xdb := NewDatabase()
+	if err := xdb.LoadSnapshotOrCtyXml(snapshot, xmlfile); err != nil {
+		t.Fatal(err)
+	}
+	if xdb.Source != xmlfile {
+		t.Errorf("Source = %q, want cty.xml %q", xdb.Source, xmlfile)
+	}
+	if !xdb.VersionDateTime.Equal(mustTime(t, "2024-02-10T12:00:00Z")) {
+		t.Errorf("VersionDateTime = %v, want the newer cty.xml", xdb.VersionDateTime)
+	}
+
+	// Broken snapshot falls back to cty.xml
+	if err := os.WriteFile(snapshot, data[:10], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bdb := NewDatabase()
+	if err := bdb.LoadSnapshotOrCtyXml(snapshot, xmlfile); err != nil {
+		t.Fatal(err)
+	}
+	if bdb.Source != xmlfile {
+		t.Errorf("Source = %q, want cty.xml %q", bdb.Source, xmlfile)
+	}
+}
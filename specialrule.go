@@ -0,0 +1,540 @@
+// gocldb declarative special rules of the callsign check
+
+package gocldb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// A special rule rewrites a value in a stage of CheckCallsign
+// when the values of the stage match, e.g., JD/M to JD1M
+// The rules of a stage are applied in order,
+// and a later rule sees the values rewritten by the earlier rules
+//
+// Stages and their values (* can be rewritten):
+//   - three_part: rewrite the reference prefix of a three-part callsign
+//     (e.g., JD/M of JA1XYZ/JD/M)
+//     rp*: reference prefix
+//   - call_area: rewrite a callsign of a single-digit call area suffix
+//     (e.g., W1AW/2 to K2AW)
+//     prefix*, area*, suffix*: letter prefix, call area digits, and suffix
+//   - two_part: rewrite the reference prefix of a two-part callsign
+//     (e.g., 3D2/C of 3D2AG/C)
+//     rp*: reference prefix,
+//     prefix1: prefix of the first part including the call area digits,
+//     part1, part2: first and second parts
+//   - zero_slash: rewrite the matched prefix of a callsign without slashes
+//     and look up the rewritten prefix (e.g., KG4 to K)
+//     prefix*: matched prefix, suffix: suffix of the callsign
+//
+// A rules file is a JSON array of the rules:
+//
+//	[
+//	  {
+//	    "name": "KC4 is Antarctica",
+//	    "stage": "two_part",
+//	    "match": {"rp": ["KC4"]},
+//	    "target": "rp",
+//	    "rewrite": "CE9",
+//	    "end": "2030-12-31T23:59:59Z"
+//	  }
+//	]
+type SpecialRule struct {
+	// Unique name of the rule
+	// A rule replaces the built-in rule of the same name
+	Name string `json:"name"`
+	// Stage of the rule, one of the RuleStage* constants
+	Stage string `json:"stage"`
+	// Patterns of the values in the path.Match() syntax
+	// Each value must match any of its patterns
+	Match map[string][]string `json:"match,omitempty"`
+	// Patterns of the values which must not match
+	Exclude map[string][]string `json:"exclude,omitempty"`
+	// Value to rewrite
+	Target string `json:"target"`
+	// New value of Target
+	// {name} is replaced by the value of name, e.g., 3D2/{part2}
+	Rewrite string `json:"rewrite"`
+	// Validity time range of the QSO time
+	// Zero Start or End is unlimited
+	Start time.Time `json:"start,omitzero"`
+	End   time.Time `json:"end,omitzero"`
+	// True to remove the built-in rule of the same name
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// Stages of the special rules
+const (
+	RuleStageThreePart = "three_part"
+	RuleStageCallArea  = "call_area"
+	RuleStageTwoPart   = "two_part"
+	RuleStageZeroSlash = "zero_slash"
+)
+
+var ErrSpecialRule = errors.New("invalid special rule")
+
+// Built-in special rules
+var builtinSpecialRules = []SpecialRule{
+	// special rules for 3D2, FO, FR of three parts are covered with inPrefixMap
+	{Name: "JD/M is Minami Torishima", Stage: RuleStageThreePart,
+		Match: map[string][]string{"rp": {"JD/M"}}, Target: "rp", Rewrite: "JD1M"},
+	{Name: "JD/O is Ogasawara", Stage: RuleStageThreePart,
+		Match: map[string][]string{"rp": {"JD/O"}}, Target: "rp", Rewrite: "JD1"},
+	{Name: "HK0/M is Malpelo", Stage: RuleStageThreePart,
+		Match: map[string][]string{"rp": {"HK0/M"}}, Target: "rp", Rewrite: "HK0M"},
+	{Name: "ZK1/S is South Cook Islands", Stage: RuleStageThreePart,
+		Match: map[string][]string{"rp": {"ZK1/S"}}, Target: "rp", Rewrite: "ZK1"},
+	{Name: "E5/S is South Cook Islands", Stage: RuleStageThreePart,
+		Match: map[string][]string{"rp": {"E5/S"}}, Target: "rp", Rewrite: "E5"},
+
+	// US prefixes: K, N, W, K[A-Z], N[A-Z], W[A-Z], A[A-L]
+	{Name: "US prefix", Stage: RuleStageCallArea,
+		Match:  map[string][]string{"prefix": {"[KNW]", "[KNW][A-Z]", "A[A-L]"}},
+		Target: "prefix", Rewrite: "K"},
+	{Name: "BS/7 is BS0 (CHINA)", Stage: RuleStageCallArea,
+		Match:  map[string][]string{"prefix": {"BS"}, "area": {"7"}},
+		Target: "area", Rewrite: "0"},
+	// Add "V" to the top of the suffix
+	// so that UA9AA/9 -> UA9VAA, RU9I/9 -> RU9VI
+	// (to Zone 18)
+	{Name: "Russian prefix/9", Stage: RuleStageCallArea,
+		Match:  map[string][]string{"prefix": {"[RU]*"}, "area": {"9"}},
+		Target: "suffix", Rewrite: "V{suffix}"},
+
+	// JJ1BDX/M and JJ1BDX/N: use the first part
+	{Name: "ignore /M or /N", Stage: RuleStageTwoPart,
+		Match:  map[string][]string{"rp": {"M", "N"}, "part2": {"M", "N"}},
+		Target: "rp", Rewrite: "{part1}"},
+	{Name: "TK/2A and TK/2B is CORSICA", Stage: RuleStageTwoPart,
+		Match:  map[string][]string{"prefix1": {"TK*"}, "part2": {"2A", "2B"}},
+		Target: "rp", Rewrite: "TK"},
+	{Name: "3D2 with /C or /S", Stage: RuleStageTwoPart,
+		Match:  map[string][]string{"prefix1": {"3D2*"}},
+		Target: "rp", Rewrite: "3D2/{part2}"},
+	{Name: "FO with /A, /C, /M", Stage: RuleStageTwoPart,
+		Match:  map[string][]string{"prefix1": {"FO*"}},
+		Target: "rp", Rewrite: "FO/{part2}"},
+	{Name: "FR with /E, /G, /J, /T", Stage: RuleStageTwoPart,
+		Match:  map[string][]string{"prefix1": {"FR*"}},
+		Target: "rp", Rewrite: "FR/{part2}"},
+	{Name: "HK0/M is HK0M", Stage: RuleStageTwoPart,
+		Match:  map[string][]string{"prefix1": {"HK0*"}},
+		Target: "rp", Rewrite: "HK0{part2}"},
+	{Name: "ZK1/N is North Cook Islands", Stage: RuleStageTwoPart,
+		Match:  map[string][]string{"prefix1": {"ZK1*"}, "part2": {"N"}},
+		Target: "rp", Rewrite: "ZK1/N"},
+	{Name: "ZK1 is South Cook Islands", Stage: RuleStageTwoPart,
+		Match:   map[string][]string{"prefix1": {"ZK1*"}},
+		Exclude: map[string][]string{"part2": {"N"}},
+		Target:  "rp", Rewrite: "ZK1"},
+	{Name: "E5/N is North Cook Islands", Stage: RuleStageTwoPart,
+		Match:  map[string][]string{"prefix1": {"E5*"}, "part2": {"N"}},
+		Target: "rp", Rewrite: "E5/N"},
+	{Name: "E5 is South Cook Islands", Stage: RuleStageTwoPart,
+		Match:   map[string][]string{"prefix1": {"E5*"}},
+		Exclude: map[string][]string{"part2": {"N"}},
+		Target:  "rp", Rewrite: "E5"},
+	{Name: "IS is Sardinia", Stage: RuleStageTwoPart,
+		Match: map[string][]string{"rp": {"IS"}}, Target: "rp", Rewrite: "IS0"},
+	{Name: "IM is Sardinia", Stage: RuleStageTwoPart,
+		Match: map[string][]string{"rp": {"IM"}}, Target: "rp", Rewrite: "IM0"},
+	{Name: "KC4 is Antarctica", Stage: RuleStageTwoPart,
+		Match: map[string][]string{"rp": {"KC4"}}, Target: "rp", Rewrite: "CE9"},
+
+	// If the suffix is 2-letter, then it remains Gitmo
+	{Name: "KG4 without 2-letter suffix is USA", Stage: RuleStageZeroSlash,
+		Match:   map[string][]string{"prefix": {"KG4"}},
+		Exclude: map[string][]string{"suffix": {"??"}},
+		Target:  "prefix", Rewrite: "K"},
+}
+
+// Values of a stage matched and rewritten by the special rules
+const (
+	ruleValueRP = iota
+	ruleValuePrefix
+	ruleValueArea
+	ruleValueSuffix
+	ruleValuePrefix1
+	ruleValuePart1
+	ruleValuePart2
+	ruleValueCount
+)
+
+type ruleValues [ruleValueCount]string
+
+var ruleValueNames = [ruleValueCount]string{
+	"rp", "prefix", "area", "suffix", "prefix1", "part1", "part2",
+}
+
+// Stages in the order of ruleStages
+const (
+	ruleStageThreePart = iota
+	ruleStageCallArea
+	ruleStageTwoPart
+	ruleStageZeroSlash
+	ruleStageCount
+)
+
+// Names, values, and rewritable values of the stages
+var ruleStages = [ruleStageCount]struct {
+	name    string
+	values  []int
+	targets []int
+}{
+	{RuleStageThreePart, []int{ruleValueRP}, []int{ruleValueRP}},
+	{RuleStageCallArea,
+		[]int{ruleValuePrefix, ruleValueArea, ruleValueSuffix},
+		[]int{ruleValuePrefix, ruleValueArea, ruleValueSuffix}},
+	{RuleStageTwoPart,
+		[]int{ruleValueRP, ruleValuePrefix1, ruleValuePart1, ruleValuePart2},
+		[]int{ruleValueRP}},
+	{RuleStageZeroSlash,
+		[]int{ruleValuePrefix, ruleValueSuffix},
+		[]int{ruleValuePrefix}},
+}
+
+// Patterns of a value
+type ruleCondition struct {
+	value    int
+	patterns []rulePattern
+}
+
+// A pattern compared without path.Match() if literal
+type rulePattern struct {
+	pattern string
+	literal bool
+}
+
+// A piece of Rewrite: the literal, or the value if value >= 0
+type ruleSegment struct {
+	literal string
+	value   int
+}
+
+// A special rule compiled for the lookups
+type compiledRule struct {
+	name    string
+	match   []ruleCondition
+	exclude []ruleCondition
+	target  int
+	rewrite []ruleSegment
+	start   time.Time
+	end     time.Time
+	// True if Start or End is set
+	limited bool
+}
+
+// Special rules compiled by the stages
+// Use NewSpecialRules() or ReadSpecialRulesFile() to create
+type SpecialRules struct {
+	// Path of the loaded rules file
+	// (empty if not loaded from a file)
+	Source string
+	// Rules after merging into the built-in rules
+	rules  []SpecialRule
+	stages [ruleStageCount][]compiledRule
+}
+
+// The built-in special rules
+var defaultSpecialRules = mustNewSpecialRules()
+
+func mustNewSpecialRules() *SpecialRules {
+	sr, err := NewSpecialRules(nil)
+	if err != nil {
+		panic(err)
+	}
+	return sr
+}
+
+// Returns a copy of the built-in special rules
+func BuiltinSpecialRules() []SpecialRule {
+	return defaultSpecialRules.Rules()
+}
+
+// Returns a copy of the rules in the applied order
+func (sr *SpecialRules) Rules() []SpecialRule {
+	rules := make([]SpecialRule, len(sr.rules))
+	copy(rules, sr.rules)
+	return rules
+}
+
+// Merge rules into the built-in rules and compile them
+// A rule replaces the built-in rule of the same name at its position,
+// or removes it if Disabled; other rules are added after the built-in rules
+// Returns ErrSpecialRule if a rule is invalid
+func NewSpecialRules(rules []SpecialRule) (*SpecialRules, error) {
+	merged := make([]SpecialRule, len(builtinSpecialRules), len(builtinSpecialRules)+len(rules))
+	copy(merged, builtinSpecialRules)
+	for _, r := range rules {
+		i := 0
+		for i < len(merged) && merged[i].Name != r.Name {
+			i++
+		}
+		switch {
+		case i < len(merged) && r.Disabled:
+			merged = append(merged[:i], merged[i+1:]...)
+		case i < len(merged):
+			merged[i] = r
+		case !r.Disabled:
+			merged = append(merged, r)
+		}
+	}
+
+	sr := &SpecialRules{rules: merged}
+	for _, r := range merged {
+		stage, cr, err := compileRule(r)
+		if err != nil {
+			return nil, err
+		}
+		sr.stages[stage] = append(sr.stages[stage], cr)
+	}
+	return sr, nil
+}
+
+// Read a JSON rules file and merge the rules into the built-in rules
+// See NewSpecialRules()
+func ReadSpecialRulesFile(filename string) (*SpecialRules, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var rules []SpecialRule
+	err = json.Unmarshal(data, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrSpecialRule, filename, err)
+	}
+	sr, err := NewSpecialRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	sr.Source = filename
+	return sr, nil
+}
+
+// Compile a rule
+// Returns the stage and the compiled rule
+func compileRule(r SpecialRule) (int, compiledRule, error) {
+	cr := compiledRule{name: r.Name, start: minTime, end: maxTime}
+	if r.Name == "" {
+		return 0, cr, fmt.Errorf("%w: empty name", ErrSpecialRule)
+	}
+	stage := 0
+	for stage < ruleStageCount && ruleStages[stage].name != r.Stage {
+		stage++
+	}
+	if stage == ruleStageCount {
+		return 0, cr, fmt.Errorf("%w: %s: unknown stage %q", ErrSpecialRule, r.Name, r.Stage)
+	}
+	values := ruleStages[stage].values
+
+	var err error
+	cr.match, err = compileConditions(r.Name, r.Match, values)
+	if err != nil {
+		return 0, cr, err
+	}
+	cr.exclude, err = compileConditions(r.Name, r.Exclude, values)
+	if err != nil {
+		return 0, cr, err
+	}
+	cr.target = ruleValueIndex(r.Target, ruleStages[stage].targets)
+	if cr.target < 0 {
+		return 0, cr, fmt.Errorf("%w: %s: target %q not in stage %s", ErrSpecialRule, r.Name, r.Target, r.Stage)
+	}
+	cr.rewrite, err = compileRewrite(r.Name, r.Rewrite, values)
+	if err != nil {
+		return 0, cr, err
+	}
+	if !r.Start.IsZero() {
+		cr.start = r.Start
+		cr.limited = true
+	}
+	if !r.End.IsZero() {
+		cr.end = r.End
+		cr.limited = true
+	}
+	return stage, cr, nil
+}
+
+// Returns the index of the value name in values, or -1 if not found
+func ruleValueIndex(name string, values []int) int {
+	for _, v := range values {
+		if ruleValueNames[v] == name {
+			return v
+		}
+	}
+	return -1
+}
+
+// Compile the patterns of the values in the order of the value indexes
+func compileConditions(rule string, m map[string][]string, values []int) ([]ruleCondition, error) {
+	for name := range m {
+		if ruleValueIndex(name, values) < 0 {
+			return nil, fmt.Errorf("%w: %s: unknown value %q", ErrSpecialRule, rule, name)
+		}
+	}
+	var conditions []ruleCondition
+	for _, v := range values {
+		patterns, exists := m[ruleValueNames[v]]
+		if !exists {
+			continue
+		}
+		c := ruleCondition{value: v}
+		for _, p := range patterns {
+			_, err := path.Match(p, "")
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: pattern %q: %w", ErrSpecialRule, rule, p, err)
+			}
+			c.patterns = append(c.patterns, rulePattern{
+				pattern: p,
+				literal: !strings.ContainsAny(p, `*?[\`),
+			})
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, nil
+}
+
+// Split Rewrite into the literals and the {name} values
+func compileRewrite(rule string, rewrite string, values []int) ([]ruleSegment, error) {
+	var segments []ruleSegment
+	for rewrite != "" {
+		i := strings.IndexByte(rewrite, '{')
+		if i < 0 {
+			segments = append(segments, ruleSegment{literal: rewrite, value: -1})
+			break
+		}
+		if i > 0 {
+			segments = append(segments, ruleSegment{literal: rewrite[:i], value: -1})
+		}
+		j := strings.IndexByte(rewrite[i:], '}')
+		if j < 0 {
+			return nil, fmt.Errorf("%w: %s: unclosed { in rewrite", ErrSpecialRule, rule)
+		}
+		name := rewrite[i+1 : i+j]
+		v := ruleValueIndex(name, values)
+		if v < 0 {
+			return nil, fmt.Errorf("%w: %s: unknown value %q in rewrite", ErrSpecialRule, rule, name)
+		}
+		segments = append(segments, ruleSegment{value: v})
+		rewrite = rewrite[i+j+1:]
+	}
+	return segments, nil
+}
+
+// True if the value matches any of the patterns
+func (c *ruleCondition) matches(v *ruleValues) bool {
+	s := v[c.value]
+	for _, p := range c.patterns {
+		if p.literal {
+			if p.pattern == s {
+				return true
+			}
+			continue
+		}
+		// The patterns are checked when compiled
+		if matched, _ := path.Match(p.pattern, s); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// True if the values match the patterns of the rule
+func (cr *compiledRule) matches(v *ruleValues) bool {
+	for i := range cr.match {
+		if !cr.match[i].matches(v) {
+			return false
+		}
+	}
+	for i := range cr.exclude {
+		if cr.exclude[i].matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the rewritten value
+func (cr *compiledRule) expand(v *ruleValues) string {
+	if len(cr.rewrite) == 1 && cr.rewrite[0].value < 0 {
+		return cr.rewrite[0].literal
+	}
+	s := ""
+	for _, seg := range cr.rewrite {
+		if seg.value < 0 {
+			s += seg.literal
+		} else {
+			s += v[seg.value]
+		}
+	}
+	return s
+}
+
+// Apply the rules of the stage to the values in order
+// The rewrites changing the values are recorded into lc if not nil
+func (sr *SpecialRules) apply(stage int, v *ruleValues, t time.Time, lc *lookupContext) {
+	rules := sr.stages[stage]
+	for i := range rules {
+		cr := &rules[i]
+		if !cr.matches(v) {
+			continue
+		}
+		if cr.limited {
+			lc.narrow(t, cr.start, cr.end)
+			if !timeInRange(t, cr.start, cr.end) {
+				continue
+			}
+		}
+		// Record only the rewrites changing the value,
+		// e.g., not K to K of the US prefix rule
+		if nv := cr.expand(v); nv != v[cr.target] {
+			v[cr.target] = lc.rewrite(v[cr.target], nv, cr.name)
+		}
+	}
+}
+
+// Returns the special rules of db, or the built-in rules if not set
+func (db *Database) specialRules() *SpecialRules {
+	if db.SpecialRules != nil {
+		return db.SpecialRules
+	}
+	return defaultSpecialRules
+}
+
+// Load a rules file and set it to db.SpecialRules
+// Do not load into a Database used by other goroutines
+func (db *Database) LoadSpecialRulesFile(filename string) error {
+	sr, err := ReadSpecialRulesFile(filename)
+	if err != nil {
+		return err
+	}
+	db.SpecialRules = sr
+	return nil
+}
+
+// Returns a shallow copy of db with the special rules set
+// The tables of db are shared, not copied
+// Set sr to nil to use the built-in rules
+func (db *Database) WithSpecialRules(sr *SpecialRules) *Database {
+	nd := *db
+	nd.SpecialRules = sr
+	return &nd
+}
+
+// Load a rules file for the default Database
+// and publish the result as the default Database
+// The tables of the current default Database are shared, not copied
+func LoadSpecialRulesFile(filename string) error {
+	sr, err := ReadSpecialRulesFile(filename)
+	if err != nil {
+		return err
+	}
+	SetDefaultDatabase(DefaultDatabase().WithSpecialRules(sr))
+	return nil
+}
@@ -0,0 +1,194 @@
+package gocldb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Calls of the fixture checked with the built-in special rules
+var specialRuleTests = []struct {
+	call string
+	adif uint16
+	// Name of the applied rule, or empty if no rule changes the call
+	rule string
+}{
+	// three_part
+	{"JA1ABC/JD/M", 177, "JD/M is Minami Torishima"},
+	{"JA1ABC/JD/O", 192, "JD/O is Ogasawara"},
+	{"K1ABC/HK0/M", 161, "HK0/M is Malpelo"},
+	{"K1ABC/ZK1/S", 234, "ZK1/S is South Cook Islands"},
+	{"K1ABC/E5/S", 234, "E5/S is South Cook Islands"},
+	// call_area
+	{"W1AW/2", 291, "US prefix"},
+	{"AA1AA/3", 291, "US prefix"},
+	// K to K is not a rewrite
+	{"K1ABC/2", 291, ""},
+	{"BS1A/7", 318, "BS/7 is BS0 (CHINA)"},
+	{"UA1AA/9", 15, "Russian prefix/9"},
+	{"JA1ABC/2", 339, ""},
+	// two_part
+	{"JA1ABC/M", 339, "ignore /M or /N"},
+	{"JA1ABC/N", 339, "ignore /M or /N"},
+	{"TK5XX/2A", 214, "TK/2A and TK/2B is CORSICA"},
+	{"TK5XX/2B", 214, "TK/2A and TK/2B is CORSICA"},
+	{"3D2AG/C", 489, "3D2 with /C or /S"},
+	{"3D2AG/R", 460, "3D2 with /C or /S"},
+	{"FO5AB/A", 508, "FO with /A, /C, /M"},
+	{"FO5AB/C", 36, "FO with /A, /C, /M"},
+	{"FO5AB/M", 509, "FO with /A, /C, /M"},
+	{"FR5AB/G", 99, "FR with /E, /G, /J, /T"},
+	{"FR5AB/J", 124, "FR with /E, /G, /J, /T"},
+	{"FR5AB/T", 276, "FR with /E, /G, /J, /T"},
+	{"HK0AB/M", 161, "HK0/M is HK0M"},
+	{"ZK1AB/N", 191, "ZK1/N is North Cook Islands"},
+	{"ZK1AB/S", 234, "ZK1 is South Cook Islands"},
+	{"E51AB/N", 191, "E5/N is North Cook Islands"},
+	{"E51AB/S", 234, "E5 is South Cook Islands"},
+	{"I1ABC/IS", 225, "IS is Sardinia"},
+	{"I1ABC/IM", 225, "IM is Sardinia"},
+	{"JA1ABC/KC4", 13, "KC4 is Antarctica"},
+	// zero_slash
+	{"KG4ABC", 291, "KG4 without 2-letter suffix is USA"},
+	{"KG4AB", 105, ""},
+}
+
+// Returns the names of the special rules applied in steps
+func appliedRules(steps []ExplainStep) []string {
+	var rules []string
+	for _, s := range steps {
+		if s.Step == StepSpecialRule {
+			rules = append(rules, s.Detail)
+		}
+	}
+	return rules
+}
+
+func TestBuiltinSpecialRules(t *testing.T) {
+	db := loadTestDatabase(t)
+	qsotime := mustTime(t, "2023-01-15T00:00:00Z")
+	tested := make(map[string]bool)
+	for _, tt := range specialRuleTests {
+		r, steps, err := db.CheckCallsignExplain(tt.call, qsotime)
+		if err != nil {
+			t.Errorf("%s: %v", tt.call, err)
+			continue
+		}
+		if r.Adif != tt.adif {
+			t.Errorf("%s: Adif = %d, want %d", tt.call, r.Adif, tt.adif)
+		}
+		rules := appliedRules(steps)
+		if tt.rule == "" {
+			if len(rules) != 0 {
+				t.Errorf("%s: rules %q applied, want none", tt.call, rules)
+			}
+			continue
+		}
+		found := false
+		for _, name := range rules {
+			found = found || name == tt.rule
+		}
+		if !found {
+			t.Errorf("%s: rules %q applied, want %q", tt.call, rules, tt.rule)
+		}
+		tested[tt.rule] = true
+	}
+	for _, r := range BuiltinSpecialRules() {
+		if !tested[r.Name] {
+			t.Errorf("built-in rule %q not tested", r.Name)
+		}
+	}
+}
+
+func TestSpecialRulesFile(t *testing.T) {
+	db := loadTestDatabase(t)
+	filename := filepath.Join(t.TempDir(), "rules.json")
+	err := os.WriteFile(filename, []byte(`[
+  {
+    "name": "KC4 is Antarctica",
+    "stage": "two_part",
+    "match": {"rp": ["KC4"]},
+    "target": "rp",
+    "rewrite": "K"
+  },
+  {
+    "name": "KG4 without 2-letter suffix is USA",
+    "disabled": true
+  },
+  {
+    "name": "JD/M is Minami Torishima",
+    "stage": "three_part",
+    "match": {"rp": ["JD/M"]},
+    "target": "rp",
+    "rewrite": "JD1M",
+    "end": "2020-12-31T23:59:59Z"
+  }
+]`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr, err := ReadSpecialRulesFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sr.Source != filename {
+		t.Errorf("Source = %q, want %q", sr.Source, filename)
+	}
+	if got, want := len(sr.Rules()), len(BuiltinSpecialRules())-1; got != want {
+		t.Errorf("%d rules, want %d", got, want)
+	}
+	rdb := db.WithSpecialRules(sr)
+
+	tests := []struct {
+		call  string
+		time  string
+		adif  uint16
+		found bool
+	}{
+		// Overridden
+		{"JA1ABC/KC4", "2023-01-15T00:00:00Z", 291, true},
+		// Disabled
+		{"KG4ABC", "2023-01-15T00:00:00Z", 105, true},
+		{"KG4AB", "2023-01-15T00:00:00Z", 105, true},
+		// Limited to the QSOs until 2020
+		{"JA1ABC/JD/M", "2019-06-01T00:00:00Z", 177, true},
+		{"JA1ABC/JD/M", "2023-01-15T00:00:00Z", 0, false},
+		// Unchanged
+		{"JA1ABC/JD/O", "2023-01-15T00:00:00Z", 192, true},
+		{"W1AW/2", "2023-01-15T00:00:00Z", 291, true},
+	}
+	for _, tt := range tests {
+		r, err := rdb.CheckCallsign(tt.call, mustTime(t, tt.time))
+		if tt.found != (err == nil) {
+			t.Errorf("%s at %s: error %v", tt.call, tt.time, err)
+		}
+		if r.Adif != tt.adif {
+			t.Errorf("%s at %s: Adif = %d, want %d", tt.call, tt.time, r.Adif, tt.adif)
+		}
+	}
+
+	// The rules of db are not changed
+	r, err := db.CheckCallsign("KG4ABC", mustTime(t, "2023-01-15T00:00:00Z"))
+	if err != nil || r.Adif != 291 {
+		t.Errorf("KG4ABC with the built-in rules: Adif = %d, %v, want 291", r.Adif, err)
+	}
+}
+
+func TestSpecialRulesInvalid(t *testing.T) {
+	tests := []SpecialRule{
+		{Name: ""},
+		{Name: "stage", Stage: "four_part"},
+		{Name: "value", Stage: RuleStageTwoPart, Match: map[string][]string{"area": {"1"}}, Target: "rp"},
+		{Name: "target", Stage: RuleStageTwoPart, Target: "part1"},
+		{Name: "pattern", Stage: RuleStageTwoPart, Match: map[string][]string{"rp": {"["}}, Target: "rp"},
+		{Name: "rewrite", Stage: RuleStageTwoPart, Target: "rp", Rewrite: "{part3}"},
+		{Name: "unclosed", Stage: RuleStageTwoPart, Target: "rp", Rewrite: "{part2"},
+	}
+	for _, r := range tests {
+		_, err := NewSpecialRules([]SpecialRule{r})
+		if !errors.Is(err, ErrSpecialRule) {
+			t.Errorf("rule %q: error %v, want ErrSpecialRule", r.Name, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// gocldb outcome of a callsign check
+
+package gocldb
+
+import (
+	"strconv"
+)
+
+// Outcome of CheckCallsign
+type Status uint8
+
+const (
+	// Not checked, e.g., a batch query cancelled before looked up
+	StatusUnknown Status = iota
+	// Matched an exception or prefix record
+	StatusValid
+	// Matched an invalid operation record
+	StatusInvalid
+	// Matched an entity but invalidated by the whitelist
+	StatusBlockedByWhitelist
+	// Aeronautical mobile (/AM) callsign
+	StatusAeronauticalMobile
+	// Maritime mobile (/MM) callsign
+	StatusMaritimeMobile
+	// No exception or prefix record matched
+	// CheckCallsign returns ErrNoMatch
+	StatusNotFound
+	// Malformed callsign
+	// CheckCallsign returns ErrMalformedCallsign
+	StatusMalformed
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusUnknown:
+		return "Unknown"
+	case StatusValid:
+		return "Valid"
+	case StatusInvalid:
+		return "Invalid"
+	case StatusBlockedByWhitelist:
+		return "BlockedByWhitelist"
+	case StatusAeronauticalMobile:
+		return "AeronauticalMobile"
+	case StatusMaritimeMobile:
+		return "MaritimeMobile"
+	case StatusNotFound:
+		return "NotFound"
+	case StatusMalformed:
+		return "Malformed"
+	}
+	return "Status(" + strconv.Itoa(int(s)) + ")"
+}
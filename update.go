@@ -0,0 +1,178 @@
+// gocldb cty.xml downloader and updater
+
+package gocldb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Base URL of Club Log cty.xml endpoint used by Update()
+// The API key is given as the "api" query parameter
+// See "Downloading The Prefixes And Exceptions As XML"
+// https://clublog.freshdesk.com/support/solutions/articles/54902-downloading-the-prefixes-and-exceptions-as-xml
+var UpdateURL = "https://cdn.clublog.org/cty.php"
+
+// Suffix of the file to store the ETag of the downloaded cty.xml
+const ETagSuffix = ".etag"
+
+// Errors
+var ErrNotNewer = errors.New("downloaded cty.xml is not newer than the current one")
+
+// Downloader and updater of cty.xml
+type Updater struct {
+	// Base URL of the cty.xml endpoint
+	// UpdateURL is used if empty
+	BaseURL string
+	// HTTP client
+	// http.DefaultClient is used if nil
+	Client *http.Client
+	// Release date and time of the current database
+	// The downloaded cty.xml must be newer than this
+	Current time.Time
+}
+
+// Download cty.xml with the API key of Club Log
+// and replace the file of destPath if newer than CLDVersionDateTime
+// of the default Database
+// See (*Updater).Update() for the details
+func Update(ctx context.Context, apiKey string, destPath string) (*Database, error) {
+	u := &Updater{Current: defaultDatabase.Load().VersionDateTime}
+	return u.Update(ctx, apiKey, destPath)
+}
+
+// Download cty.xml with the API key of Club Log
+// and replace the file of destPath
+//
+// The request is conditional with If-None-Match
+// (the ETag stored in destPath + ETagSuffix)
+// and If-Modified-Since (the modification time of destPath).
+// The downloaded contents are decompressed and parsed,
+// then written into destPath by an atomic rename
+// only if the release date is newer than u.Current.
+//
+// Returns the Database of the new cty.xml,
+// nil if not modified on the server,
+// or ErrNotNewer if the downloaded cty.xml is not newer
+func (u *Updater) Update(ctx context.Context, apiKey string, destPath string) (*Database, error) {
+	baseURL := u.BaseURL
+	if baseURL == "" {
+		baseURL = UpdateURL
+	}
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	reqURL, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	query := reqURL.Query()
+	query.Set("api", apiKey)
+	reqURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Set conditional request headers only if destPath exists
+	fi, err := os.Stat(destPath)
+	if err == nil {
+		req.Header.Set("If-Modified-Since", fi.ModTime().UTC().Format(http.TimeFormat))
+		etag, err := os.ReadFile(destPath + ETagSuffix)
+		if err == nil && len(etag) > 0 {
+			req.Header.Set("If-None-Match", strings.TrimSpace(string(etag)))
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("cty.xml update: HTTP status %s", resp.Status)
+	}
+
+	// Decompress if needed
+	buf, err := readCtyXml(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	db := NewDatabase()
+	err = db.LoadCtyXmlReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	if !db.VersionDateTime.After(u.Current) {
+		return nil, ErrNotNewer
+	}
+
+	err = writeFileAtomic(destPath, buf)
+	if err != nil {
+		return nil, err
+	}
+	db.Source = destPath
+
+	// Keep the Last-Modified time for If-Modified-Since
+	lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err == nil {
+		err = os.Chtimes(destPath, lastModified, lastModified)
+		if err != nil {
+			return db, err
+		}
+	}
+	// Keep the ETag for If-None-Match
+	etag := resp.Header.Get("ETag")
+	if etag != "" {
+		err = os.WriteFile(destPath+ETagSuffix, []byte(etag+"\n"), 0644)
+	} else {
+		err = os.Remove(destPath + ETagSuffix)
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	}
+
+	return db, err
+}
+
+// Write data to a temporary file in the same directory
+// then rename it to filename
+func writeFileAtomic(filename string, data []byte) error {
+	fp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+"-*")
+	if err != nil {
+		return err
+	}
+	tmpname := fp.Name()
+	_, err = fp.Write(data)
+	if err == nil {
+		err = fp.Sync()
+	}
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmpname, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpname, filename)
+	}
+	if err != nil {
+		os.Remove(tmpname)
+	}
+	return err
+}
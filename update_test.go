@@ -0,0 +1,139 @@
+package gocldb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Returns data compressed with gzip
+func gzipData(tb testing.TB, data []byte) []byte {
+	tb.Helper()
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write(data); err != nil {
+		tb.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestUpdater(t *testing.T) {
+	data, err := os.ReadFile(testCtyXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Same contents released before the fixture
+	older := bytes.Replace(data, []byte(`date="2024-01-10T12:00:00+00:00"`),
+		[]byte(`date="2023-06-01T00:00:00+00:00"`), 1)
+	lastModified := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	const etag = `"cty-20240110"`
+	const apiKey = "test-api-key"
+
+	// Response of the server for the next request
+	var status int
+	var body []byte
+	// Headers of the last request
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("api"); got != apiKey {
+			t.Errorf("api = %q, want %q", got, apiKey)
+		}
+		header = r.Header.Clone()
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	destPath := filepath.Join(t.TempDir(), "cty.xml")
+	ctx := context.Background()
+	u := &Updater{BaseURL: srv.URL, Client: srv.Client()}
+
+	// 200 with a gzip body
+	status, body = http.StatusOK, gzipData(t, data)
+	db, err := u.Update(ctx, apiKey, destPath)
+	if err != nil {
+		t.Fatalf("200: %v", err)
+	}
+	if db == nil || !db.VersionDateTime.Equal(mustTime(t, "2024-01-10T12:00:00Z")) {
+		t.Fatalf("200: Database %v", db)
+	}
+	if db.Source != destPath {
+		t.Errorf("200: Source = %q, want %q", db.Source, destPath)
+	}
+	if header.Get("If-None-Match") != "" || header.Get("If-Modified-Since") != "" {
+		t.Errorf("200: conditional headers without destPath: %v", header)
+	}
+	written, err := os.ReadFile(destPath)
+	if err != nil || !bytes.Equal(written, data) {
+		t.Errorf("200: destPath not the decompressed cty.xml: %v", err)
+	}
+	fi, err := os.Stat(destPath)
+	if err != nil || !fi.ModTime().Equal(lastModified) {
+		t.Errorf("200: modification time not Last-Modified: %v", err)
+	}
+	sidecar, err := os.ReadFile(destPath + ETagSuffix)
+	if err != nil || string(sidecar) != etag+"\n" {
+		t.Errorf("200: ETag file = %q, %v, want %q", sidecar, err, etag)
+	}
+
+	// 304 to the conditional request
+	status = http.StatusNotModified
+	u.Current = db.VersionDateTime
+	db, err = u.Update(ctx, apiKey, destPath)
+	if db != nil || err != nil {
+		t.Errorf("304: %v, %v, want nil, nil", db, err)
+	}
+	if got := header.Get("If-None-Match"); got != etag {
+		t.Errorf("304: If-None-Match = %q, want %q", got, etag)
+	}
+	if got, want := header.Get("If-Modified-Since"), lastModified.Format(http.TimeFormat); got != want {
+		t.Errorf("304: If-Modified-Since = %q, want %q", got, want)
+	}
+
+	// 200 with a release not newer than Current
+	status, body = http.StatusOK, gzipData(t, older)
+	db, err = u.Update(ctx, apiKey, destPath)
+	if db != nil || !errors.Is(err, ErrNotNewer) {
+		t.Errorf("not newer: %v, %v, want ErrNotNewer", db, err)
+	}
+	written, err = os.ReadFile(destPath)
+	if err != nil || !bytes.Equal(written, data) {
+		t.Errorf("not newer: destPath changed: %v", err)
+	}
+	fi, err = os.Stat(destPath)
+	if err != nil || !fi.ModTime().Equal(lastModified) {
+		t.Errorf("not newer: modification time changed: %v", err)
+	}
+
+	// Server error
+	status = http.StatusInternalServerError
+	db, err = u.Update(ctx, apiKey, destPath)
+	if db != nil || err == nil {
+		t.Errorf("500: %v, %v, want an error", db, err)
+	}
+
+	// No temporary files left
+	entries, err := os.ReadDir(filepath.Dir(destPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("%d files in the directory, want cty.xml and its ETag file", len(entries))
+	}
+}
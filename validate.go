@@ -0,0 +1,214 @@
+// gocldb database consistency checker
+
+package gocldb
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Names of the checks in Problem.Check
+const (
+	CheckOverlap         = "overlap"
+	CheckEndBeforeStart  = "end_before_start"
+	CheckUnknownAdif     = "unknown_adif"
+	CheckEntityName      = "entity_name"
+	CheckCqzRange        = "cqz_range"
+	CheckContinent       = "continent"
+	CheckDuplicateRecord = "duplicate_record"
+)
+
+// Section names in Problem.Section, same as the cty.xml elements
+const (
+	SectionEntities          = "entities"
+	SectionExceptions        = "exceptions"
+	SectionPrefixes          = "prefixes"
+	SectionInvalidOperations = "invalid_operations"
+	SectionZoneExceptions    = "zone_exceptions"
+)
+
+// Valid continent values of ADIF Field CONT
+var adifContinents = map[string]bool{
+	"AF": true, "AN": true, "AS": true, "EU": true,
+	"NA": true, "OC": true, "SA": true,
+}
+
+// Range of CQ Zone Number
+const (
+	CqzMin = 1
+	CqzMax = 40
+)
+
+// A data consistency problem found by Validate()
+type Problem struct {
+	// Name of the check, e.g., CheckOverlap
+	Check string `json:"check"`
+	// Section name, e.g., SectionExceptions
+	Section string `json:"section"`
+	// Record number (ADIF entity code for entities)
+	Record uint64 `json:"record"`
+	// Related record number for CheckOverlap
+	OtherRecord uint64 `json:"other_record,omitempty"`
+	// Map key: callsign or prefix
+	Key string `json:"key"`
+	// Human-readable description
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s record %d (%s): %s",
+		p.Check, p.Section, p.Record, p.Key, p.Message)
+}
+
+// Check the loaded tables of db for data consistency problems
+// Map keys are scanned in the sorted order for reproducible results
+// Returns the problems sorted by section, record, and check
+func (db *Database) Validate() []Problem {
+	var problems []Problem
+	add := func(check, section string, record uint64, key string, format string, args ...any) {
+		problems = append(problems, Problem{
+			Check:   check,
+			Section: section,
+			Record:  record,
+			Key:     key,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	checkRange := func(section string, record uint64, key string, start, end time.Time) {
+		if end.Before(start) {
+			add(CheckEndBeforeStart, section, record, key,
+				"end %s before start %s",
+				end.Format(ClublogTimeLayout), start.Format(ClublogTimeLayout))
+		}
+	}
+	checkCqz := func(section string, record uint64, key string, cqz uint8) {
+		if cqz < CqzMin || cqz > CqzMax {
+			add(CheckCqzRange, section, record, key,
+				"CQ zone %d out of range %d-%d", cqz, CqzMin, CqzMax)
+		}
+	}
+	checkCont := func(section string, record uint64, key string, cont string) {
+		if cont != "" && !adifContinents[cont] {
+			add(CheckContinent, section, record, key,
+				"continent %q not in ADIF CONT", cont)
+		}
+	}
+	checkAdif := func(section string, record uint64, key string, adif uint16, entity string) {
+		e, exists := db.MapEntityByAdif[adif]
+		if !exists {
+			add(CheckUnknownAdif, section, record, key,
+				"ADIF entity code %d not in entities", adif)
+			return
+		}
+		if entity != e.Name {
+			add(CheckEntityName, section, record, key,
+				"entity name %q differs from %q of ADIF entity code %d",
+				entity, e.Name, adif)
+		}
+	}
+	// The overlaps are detected by the interval index of the records
+	checkOverlap := func(section string, key string, idx *intervalIndex, record func(int) uint64) {
+		idx.overlaps(func(i, j int) {
+			problems = append(problems, Problem{
+				Check:       CheckOverlap,
+				Section:     section,
+				Record:      record(i),
+				OtherRecord: record(j),
+				Key:         key,
+				Message:     fmt.Sprintf("time range overlaps with record %d", record(j)),
+			})
+		})
+	}
+	checkDuplicate := func(section string, seen map[uint64]string, record uint64, key string) {
+		if other, exists := seen[record]; exists {
+			add(CheckDuplicateRecord, section, record, key,
+				"record number also used for %s", other)
+			return
+		}
+		seen[record] = key
+	}
+
+	seen := make(map[uint64]string)
+	for _, prefix := range slices.Sorted(maps.Keys(db.MapEntity)) {
+		entities := db.MapEntity[prefix]
+		for _, e := range entities {
+			record := uint64(e.Adif)
+			checkDuplicate(SectionEntities, seen, record, prefix)
+			checkRange(SectionEntities, record, prefix, e.Start, e.End)
+			if e.Whitelist {
+				checkRange(SectionEntities, record, prefix, e.WhitelistStart, e.WhitelistEnd)
+			}
+			checkCqz(SectionEntities, record, prefix, e.Cqz)
+			checkCont(SectionEntities, record, prefix, e.Cont)
+		}
+	}
+
+	seen = make(map[uint64]string)
+	for _, call := range slices.Sorted(maps.Keys(db.MapException)) {
+		exceptions := db.MapException[call]
+		for _, e := range exceptions {
+			checkDuplicate(SectionExceptions, seen, e.Record, call)
+			checkRange(SectionExceptions, e.Record, call, e.Start, e.End)
+			checkAdif(SectionExceptions, e.Record, call, e.Adif, e.Entity)
+			checkCqz(SectionExceptions, e.Record, call, e.Cqz)
+			checkCont(SectionExceptions, e.Record, call, e.Cont)
+		}
+		checkOverlap(SectionExceptions, call, newIntervalIndex(exceptions),
+			func(i int) uint64 { return exceptions[i].Record })
+	}
+
+	seen = make(map[uint64]string)
+	for _, call := range slices.Sorted(maps.Keys(db.MapPrefix)) {
+		prefixes := db.MapPrefix[call]
+		for _, e := range prefixes {
+			checkDuplicate(SectionPrefixes, seen, e.Record, call)
+			checkRange(SectionPrefixes, e.Record, call, e.Start, e.End)
+			checkAdif(SectionPrefixes, e.Record, call, e.Adif, e.Entity)
+			checkCqz(SectionPrefixes, e.Record, call, e.Cqz)
+			checkCont(SectionPrefixes, e.Record, call, e.Cont)
+		}
+		checkOverlap(SectionPrefixes, call, newIntervalIndex(prefixes),
+			func(i int) uint64 { return prefixes[i].Record })
+	}
+
+	seen = make(map[uint64]string)
+	for _, call := range slices.Sorted(maps.Keys(db.MapInvalid)) {
+		invalids := db.MapInvalid[call]
+		for _, e := range invalids {
+			checkDuplicate(SectionInvalidOperations, seen, e.Record, call)
+			checkRange(SectionInvalidOperations, e.Record, call, e.Start, e.End)
+		}
+	}
+
+	seen = make(map[uint64]string)
+	for _, call := range slices.Sorted(maps.Keys(db.MapZoneException)) {
+		zoneexceptions := db.MapZoneException[call]
+		for _, e := range zoneexceptions {
+			checkDuplicate(SectionZoneExceptions, seen, e.Record, call)
+			checkRange(SectionZoneExceptions, e.Record, call, e.Start, e.End)
+			checkCqz(SectionZoneExceptions, e.Record, call, e.Zone)
+		}
+		checkOverlap(SectionZoneExceptions, call, newIntervalIndex(zoneexceptions),
+			func(i int) uint64 { return zoneexceptions[i].Record })
+	}
+
+	slices.SortFunc(problems, func(a, b Problem) int {
+		return cmp.Or(
+			cmp.Compare(a.Section, b.Section),
+			cmp.Compare(a.Record, b.Record),
+			cmp.Compare(a.Check, b.Check),
+			cmp.Compare(a.OtherRecord, b.OtherRecord),
+			cmp.Compare(a.Key, b.Key),
+		)
+	})
+	return problems
+}
+
+// Check the default Database for data consistency problems
+// See (*Database).Validate()
+func Validate() []Problem {
+	return defaultDatabase.Load().Validate()
+}
@@ -0,0 +1,44 @@
+package gocldb
+
+import (
+	"testing"
+)
+
+// Each check fails exactly once in this file
+const testBrokenXml = "testdata/broken.xml"
+
+func TestValidate(t *testing.T) {
+	db := NewDatabase()
+	err := db.LoadCtyXmlFile(testBrokenXml)
+	if err != nil {
+		t.Fatalf("LoadCtyXmlFile(%q): %v", testBrokenXml, err)
+	}
+	// In the sorted order of Validate()
+	want := []Problem{
+		{Check: CheckOverlap, Section: SectionExceptions, Record: 2, OtherRecord: 1, Key: "JA1XYZ"},
+		{Check: CheckUnknownAdif, Section: SectionExceptions, Record: 3, Key: "JA1ABC"},
+		{Check: CheckEndBeforeStart, Section: SectionInvalidOperations, Record: 200, Key: "JA1INV"},
+		{Check: CheckDuplicateRecord, Section: SectionPrefixes, Record: 100, Key: "JJ"},
+		{Check: CheckEntityName, Section: SectionPrefixes, Record: 101, Key: "JD1M"},
+		{Check: CheckContinent, Section: SectionPrefixes, Record: 102, Key: "JR"},
+		{Check: CheckCqzRange, Section: SectionZoneExceptions, Record: 300, Key: "W1AW"},
+	}
+	got := db.Validate()
+	if len(got) != len(want) {
+		t.Fatalf("Validate() = %d problems, want %d: %v", len(got), len(want), got)
+	}
+	for i, p := range got {
+		if p.Message == "" {
+			t.Errorf("Validate()[%d] = %v: empty Message", i, p)
+		}
+		p.Message = ""
+		if p != want[i] {
+			t.Errorf("Validate()[%d] = %+v, want %+v", i, p, want[i])
+		}
+	}
+
+	// The test data is consistent
+	if problems := loadTestDatabase(t).Validate(); len(problems) != 0 {
+		t.Errorf("Validate() of %s = %v, want none", testCtyXml, problems)
+	}
+}
@@ -0,0 +1,192 @@
+// gocldb cty.xml file watcher for hot reloading
+
+package gocldb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrWatchInterval = errors.New("watch interval must be positive")
+
+// Callback function called after reloading
+// with CLDVersionDateTime of the old and new Database
+type ReloadFunc func(oldVersion time.Time, newVersion time.Time)
+
+// Watcher polls a cty.xml file for changes,
+// loads the changed file, and swaps the Database atomically
+// Lookups through the Watcher use a consistent snapshot
+// of the Database at the time of the call
+type Watcher struct {
+	filename string
+	interval time.Duration
+	opts     []Option
+	current  atomic.Pointer[Database]
+
+	// Protects the fields below
+	mu        sync.Mutex
+	callbacks []ReloadFunc
+	modTime   time.Time
+	size      int64
+	// True if publishing to the default Database
+	publish bool
+}
+
+// Load the cty.xml file of filename and return a Watcher
+// polling the file every interval after Run() is called
+// The options are applied to the loaded Databases
+// Returns ErrWatchInterval if interval is not positive
+func NewWatcher(filename string, interval time.Duration, opts ...Option) (*Watcher, error) {
+	if interval <= 0 {
+		return nil, ErrWatchInterval
+	}
+	w := &Watcher{
+		filename: filename,
+		interval: interval,
+		opts:     opts,
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	db := NewDatabase(opts...)
+	err = db.LoadCtyXmlFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	w.modTime = fi.ModTime()
+	w.size = fi.Size()
+	w.current.Store(db)
+	return w, nil
+}
+
+// Returns the current snapshot of the Database
+func (w *Watcher) Database() *Database {
+	return w.current.Load()
+}
+
+// Parse a callsign and time with the current snapshot of the Database
+// See (*Database).CheckCallsign()
+func (w *Watcher) CheckCallsign(call string, qsotime time.Time) (CLDCheckResult, error) {
+	return w.current.Load().CheckCallsign(call, qsotime)
+}
+
+// Set the overlay on top of the current snapshot of the Database
+// The overlay is kept over the reloads
+// Set overlay to nil to remove the overlay
+func (w *Watcher) SetOverlay(overlay *Database) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.store(w.current.Load().WithOverlay(overlay))
+}
+
+// Set the special rules of the current snapshot of the Database
+// The rules are kept over the reloads
+// Set sr to nil to use the built-in rules
+func (w *Watcher) SetSpecialRules(sr *SpecialRules) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.store(w.current.Load().WithSpecialRules(sr))
+}
+
+// Publish the current snapshot of the Database
+// and the later ones as the default Database,
+// so the package-level functions such as CheckCallsign()
+// see the reloads
+func (w *Watcher) PublishDefault() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.publish = true
+	SetDefaultDatabase(w.current.Load())
+}
+
+// Set the current snapshot of the Database
+// and publish it if PublishDefault() is called
+// Returns the old snapshot
+// Call with w.mu locked
+func (w *Watcher) store(db *Database) *Database {
+	old := w.current.Swap(db)
+	if w.publish {
+		SetDefaultDatabase(db)
+	}
+	return old
+}
+
+// Register a callback function called after each reload
+// The callbacks are called without the lock of the Watcher,
+// so a callback may call the methods of the Watcher
+func (w *Watcher) OnReload(f ReloadFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callbacks = append(w.callbacks, f)
+}
+
+// Poll the file every interval and reload it if changed
+// until ctx is done; run this in a goroutine
+// Reload errors are logged to the logger of the current Database
+// and the current Database is kept
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			_, err := w.Check()
+			if err != nil {
+				w.current.Load().Logger().Warn("Watcher: reload failed", "file", w.filename, "error", err)
+			}
+		}
+	}
+}
+
+// Check the file once and reload it if the modification time
+// or the size is changed
+// Returns true if reloaded
+// The file is checked again at the next call if the reload fails
+func (w *Watcher) Check() (bool, error) {
+	old, db, callbacks, err := w.reload()
+	if db == nil {
+		return false, err
+	}
+	for _, f := range callbacks {
+		f(old.VersionDateTime, db.VersionDateTime)
+	}
+	return true, nil
+}
+
+// Reload the file if changed
+// Returns the old and new Databases and the callbacks to call,
+// or nil Databases if not reloaded
+func (w *Watcher) reload() (*Database, *Database, []ReloadFunc, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	fi, err := os.Stat(w.filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if fi.ModTime().Equal(w.modTime) && fi.Size() == w.size {
+		return nil, nil, nil, nil
+	}
+	db := NewDatabase(w.opts...)
+	err = db.LoadCtyXmlFile(w.filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	db.Overlay = w.current.Load().Overlay
+	db.SpecialRules = w.current.Load().SpecialRules
+	w.modTime = fi.ModTime()
+	w.size = fi.Size()
+
+	old := w.store(db)
+	// Copy the callbacks to call them after unlocking
+	callbacks := slices.Clone(w.callbacks)
+	return old, db, callbacks, nil
+}
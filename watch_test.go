@@ -0,0 +1,84 @@
+package gocldb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	old := DefaultDatabase()
+	t.Cleanup(func() { SetDefaultDatabase(old) })
+	data, err := os.ReadFile(testCtyXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "cty.xml")
+	write := func(s string, modTime time.Time) {
+		t.Helper()
+		err := os.WriteFile(filename, []byte(s), 0o644)
+		if err == nil {
+			err = os.Chtimes(filename, modTime, modTime)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	modTime := time.Now().Add(-time.Hour)
+	write(string(data), modTime)
+
+	if _, err := NewWatcher(filename, 0); !errors.Is(err, ErrWatchInterval) {
+		t.Errorf("NewWatcher() interval 0: %v, want ErrWatchInterval", err)
+	}
+	w, err := NewWatcher(filename, time.Minute)
+	if err != nil {
+		t.Fatalf("NewWatcher(): %v", err)
+	}
+	first := w.Database()
+	var versions [][2]time.Time
+	w.OnReload(func(oldVersion, newVersion time.Time) {
+		versions = append(versions, [2]time.Time{oldVersion, newVersion})
+	})
+
+	// Not reloaded if not changed
+	if reloaded, err := w.Check(); reloaded || err != nil {
+		t.Errorf("Check() unchanged = %t, %v, want false", reloaded, err)
+	}
+
+	// Reloaded and published
+	w.PublishDefault()
+	if DefaultDatabase() != first {
+		t.Errorf("PublishDefault(): default Database not published")
+	}
+	const date = "2024-01-10T12:00:00+00:00"
+	const newDate = "2024-02-10T12:00:00+00:00"
+	modTime = modTime.Add(time.Minute)
+	write(strings.Replace(string(data), date, newDate, 1), modTime)
+	if reloaded, err := w.Check(); !reloaded || err != nil {
+		t.Fatalf("Check() changed = %t, %v, want true", reloaded, err)
+	}
+	second := w.Database()
+	if second == first || DefaultDatabase() != second {
+		t.Errorf("Check(): Database not swapped or not published")
+	}
+	want := [2]time.Time{mustTime(t, date), mustTime(t, newDate)}
+	if len(versions) != 1 || !versions[0][0].Equal(want[0]) || !versions[0][1].Equal(want[1]) {
+		t.Errorf("OnReload() versions = %v, want %v", versions, want)
+	}
+	if r, err := w.CheckCallsign("JA1ABC", mustTime(t, "2023-01-15T00:00:00Z")); err != nil || r.Adif != 339 {
+		t.Errorf("CheckCallsign(JA1ABC) = %d, %v", r.Adif, err)
+	}
+
+	// The current Database is kept if the reload fails
+	modTime = modTime.Add(time.Minute)
+	write("<clublog", modTime)
+	if reloaded, err := w.Check(); reloaded || err == nil {
+		t.Errorf("Check() broken = %t, %v, want an error", reloaded, err)
+	}
+	if w.Database() != second || len(versions) != 1 {
+		t.Errorf("Check() broken: Database replaced")
+	}
+}